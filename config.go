@@ -0,0 +1,592 @@
+package pretty
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A Config holds the options used to print values.
+// The package-level functions, such as Fprint, print with a Config
+// made from the package-level options of the same names.
+//
+// The zero Config prints values as Fprint does
+// with each package-level option at its default.
+// An empty Indent, CyclePlaceholder, or TruncationMarker
+// is replaced by the default.
+type Config struct {
+	// Indent is the string used for each level of indentation.
+	// If Indent is empty, "\t" is used.
+	Indent string
+
+	// EmptyString, if non-empty, is printed in place of
+	// empty string values; for example, `""(empty)`.
+	EmptyString string
+
+	// CyclePlaceholder is printed in place of a value
+	// that is already on the path from the root.
+	// If CyclePlaceholder is empty, "<cycle>" is used.
+	CyclePlaceholder string
+
+	// TruncationMarker marks output that has been elided,
+	// such as the end of a string cut by MaxStringLen.
+	// If TruncationMarker is empty, "…" is used.
+	TruncationMarker string
+
+	// InlineUnder, if positive, causes structs, arrays, and slices
+	// to be printed on one line if that line would be shorter
+	// than InlineUnder characters.
+	InlineUnder int
+
+	// MaxOutputBytes, if positive, is the maximum number of bytes
+	// written for a value, including any LinePrefix and LineNumbers,
+	// but not counting a final truncation message.
+	// Longer output is cut short and ErrTruncated is returned.
+	// With LineNumbers, the numbers are as wide as needed
+	// for the lines printed before the output is cut short.
+	MaxOutputBytes int
+
+	// MaxStringLen, if positive, is the maximum number of bytes
+	// of a string value that are printed; for example, "abc…" (+4096 bytes).
+	MaxStringLen int
+
+	// SyncMaps, if true, causes sync.Map values to be printed
+	// like maps, with sorted entries, rather than as structs.
+	SyncMaps bool
+
+	// ShowUnexported, if true, causes the unexported fields
+	// of addressable structs to be printed.
+	ShowUnexported bool
+
+	// UseStringer, if true, causes fmt.Stringers
+	// to be printed with their String method.
+	UseStringer bool
+
+	// UseTextMarshaler, if true, causes encoding.TextMarshalers
+	// to be printed as the quoted result of MarshalText,
+	// unless a Printer or, with UseStringer, a fmt.Stringer is used.
+	UseTextMarshaler bool
+
+	// Summary, if true, causes a trailing line to be printed
+	// giving the number of values printed and the size of the output
+	// before it, including any LinePrefix and LineNumbers;
+	// for example, # 3421 nodes, 18KB.
+	Summary bool
+
+	// CanonicalFloats, if true, causes negative zero to print as zero
+	// and all NaNs to print alike.
+	CanonicalFloats bool
+
+	// ShowTopType, if true, causes the printed value
+	// to be preceded by its type name if it is of a named type;
+	// for example, List[…].
+	ShowTopType bool
+
+	// ShowNamedScalars, if true, causes values of named bool,
+	// numeric, and string types to be printed with their type name;
+	// for example, Celsius(37.000000).
+	ShowNamedScalars bool
+
+	// ShowIndirection, if true, causes each pointer to be printed
+	// as & followed by the value it points to; for example, &&T{…}.
+	ShowIndirection bool
+
+	// OmitZero, if true, causes zero-valued struct fields to be omitted,
+	// with TruncationMarker printed after the remaining fields.
+	OmitZero bool
+
+	// ShowInterfaceType, if true, causes each non-nil value
+	// held in an interface to be preceded by the interface type
+	// and its dynamic type; for example, io.Reader(*os.File) File{…}.
+	ShowInterfaceType bool
+
+	// MapKeysOnly, if true, causes maps to be printed
+	// as a single-line list of their sorted keys.
+	MapKeysOnly bool
+
+	// Sparse, if true, causes arrays and slices to be printed
+	// with each non-zero element preceded by its index
+	// and each run of zero elements collapsed into one line.
+	Sparse bool
+
+	// BoolBitmap, if true, causes arrays and slices of bool
+	// to be printed on one line as 1s and 0s; for example, [1 0 1].
+	BoolBitmap bool
+
+	// ShowFieldTypes, if true, causes each struct field name
+	// to be followed by its declared type; for example, A int: 5.
+	ShowFieldTypes bool
+
+	// FlattenEmbedded, if true, causes the fields of an embedded struct
+	// to be printed in place of the embedded field,
+	// qualified by the embedded type name if they would collide;
+	// for example, Inner.X.
+	FlattenEmbedded bool
+
+	// DedupPointers, if true, causes each pointer that occurs
+	// more than once within the printed value to be printed in full
+	// only at its first occurrence, preceded by a number; for example, #1 T{…}.
+	// Later occurrences are printed as ↺ and the number; for example, ↺ #1.
+	DedupPointers bool
+
+	// LinePrefix is written at the beginning of each line of output,
+	// including the first.
+	LinePrefix string
+
+	// FieldVisible, if non-nil, reports whether a struct field is printed,
+	// in place of the default rule: exported fields are printed,
+	// and unexported fields only if ShowUnexported is true.
+	// As with ShowUnexported, unexported fields are only printed
+	// if the struct is addressable.
+	// An embedded struct field that is not visible is not flattened
+	// by FlattenEmbedded.
+	FieldVisible func(reflect.StructField) bool
+
+	// FloatFormat is the fmt format used to print floating point values
+	// and the parts of complex values; for example, "%g" or "%.2f".
+	// If FloatFormat is empty, "%f" is used.
+	FloatFormat string
+
+	// AlignFields, if true, causes the values of a struct
+	// printed on multiple lines to be aligned in a column,
+	// if none of its fields are structs, arrays, slices, or maps.
+	AlignFields bool
+
+	// ShowPointers, if true, causes uintptr values to be printed in hex,
+	// and unsafe.Pointer values to be printed as their address in hex,
+	// or nil, rather than as <unsafe pointer>.
+	ShowPointers bool
+
+	// OpenStruct and CloseStruct, if non-empty, replace the braces
+	// around the fields of a struct; for example, "(" and ")" print T(A: 1).
+	OpenStruct, CloseStruct string
+	// OpenArray and CloseArray, if non-empty, replace the brackets
+	// around the elements of an array or slice.
+	OpenArray, CloseArray string
+	// OpenMap and CloseMap, if non-empty, replace the braces
+	// around the entries of a map.
+	OpenMap, CloseMap string
+
+	// RawStringer, if true, causes values of integer, floating point,
+	// and complex kinds to be printed as numbers even if UseStringer is true,
+	// so a time.Duration prints as 1500000000 rather than 1.5s.
+	// RawStringer has no effect if UseStringer is false.
+	RawStringer bool
+
+	// MapSort is the order in which map entries are printed.
+	MapSort MapOrder
+
+	// IgnoreMissingPaths, if true, causes PrintPath to ignore
+	// paths that do not name a value, rather than returning an error.
+	IgnoreMissingPaths bool
+
+	// Newline is the line break written between lines of output.
+	// If Newline is empty, "\n" is used.
+	Newline string
+
+	// CallGetters, if true, causes a struct with no fields to print
+	// to be printed with the results of its exported methods
+	// that take no arguments and return a single value,
+	// as pseudo-fields; for example, T{Len() -> 5}.
+	// Methods of *T are used if the struct is addressable.
+	// A method that panics is printed as <error>.
+	// Getters are not called on the results of getters.
+	//
+	// CallGetters calls arbitrary methods, which may have side effects.
+	// Each is called at most once for a value in each print.
+	CallGetters bool
+
+	// SkipKinds lists kinds of struct fields that are not printed,
+	// such as reflect.Func or reflect.Chan.
+	// A field of interface type is also skipped
+	// if the kind of its dynamic value is listed.
+	// As with OmitZero, TruncationMarker is printed
+	// after the remaining fields if any are skipped.
+	SkipKinds []reflect.Kind
+
+	// VerboseCycles, if true, causes each cycle to be printed
+	// with the path from the root to the value it refers back to,
+	// made of field names, indices, and map keys;
+	// for example, <cycle -> /D/X>.
+	VerboseCycles bool
+
+	// InlineScalarMaps, if true, causes maps whose values are
+	// not structs, arrays, slices, or maps to be printed on a single line,
+	// like {"a": 5, "b": 6}.
+	InlineScalarMaps bool
+
+	// DescribeIO, if true, causes values that implement io.Reader
+	// or io.Writer to be printed as a short description
+	// rather than traversed: the type, followed by
+	// the quoted result of a Name method, if any,
+	// or a few details for common types such as *bytes.Buffer;
+	// for example, <*os.File "/tmp/x"> or <*bytes.Buffer len=5>.
+	DescribeIO bool
+
+	// MatrixLayout, if true, causes an array or slice
+	// of arrays or slices of the same non-zero length
+	// whose elements are not structs, arrays, slices, maps, or pointers
+	// to be printed as a grid, one row per line,
+	// with the elements in each column aligned.
+	MatrixLayout bool
+
+	// Separator is the line printed between values by FprintAll.
+	// If Separator is empty, a blank line is printed.
+	Separator string
+
+	// RunesAsChars, if true, causes int32 values in struct fields
+	// tagged `pretty:"rune"`, and the int32 values within them,
+	// to be printed as quoted characters, as by strconv.QuoteRune;
+	// for example, 'α' rather than 945.
+	// Since rune is an alias for int32, the type cannot tell them apart,
+	// so the tag is needed.
+	RunesAsChars bool
+
+	// MaxFields, if positive, is the maximum number of fields
+	// printed for each struct. The remaining fields are replaced by
+	// TruncationMarker and their number; for example, … (+3 more).
+	// Fields that are not printed for other reasons are not counted.
+	MaxFields int
+
+	// NullWrappers, if true, causes structs like sql.NullString,
+	// with two exported fields, one of which is Valid, a bool,
+	// to be printed as the value of the other field if Valid is true,
+	// and as null otherwise.
+	NullWrappers bool
+
+	// WrapWidth, if positive, is the number of columns
+	// beyond which strings are wrapped onto the following lines,
+	// split into quoted pieces joined by +.
+	// Other values are not wrapped, so lines may still be longer.
+	// Tabs count as advancing to the next multiple of 8 columns.
+	WrapWidth int
+
+	// Width, if positive, causes each struct, array, slice, or map
+	// to be printed on a single line if the line would then be
+	// at most Width columns long, and otherwise on multiple lines,
+	// with the values within it each printed on a single line if they fit.
+	// If InlineUnder is also set, values must satisfy both.
+	// Width counts columns as WrapWidth does.
+	Width int
+
+	// ShowLenCap, if true, causes each array or slice to be preceded
+	// by its type and length, and for slices, capacity;
+	// for example, []int(len 3, cap 8)[1, 2, 3].
+	ShowLenCap bool
+
+	// MapKeyLess, if non-nil, reports whether the map key a
+	// is ordered before the map key b, in place of the default order;
+	// for example, to order string keys ignoring case.
+	// Keys that are equal according to MapKeyLess
+	// are ordered in the default order, so the order is the same on each call.
+	// If MapSort is ByValue, MapKeyLess orders entries with equal values.
+	MapKeyLess func(a, b reflect.Value) bool
+
+	// ScrubString, if non-nil, is applied to each string value,
+	// including map keys, their locations printed by VerboseCycles,
+	// and rune slices printed by RuneSlicesAsString, before it is printed;
+	// for example, to mask secrets.
+	// It is not applied to the results of PrettyPrint or String methods.
+	ScrubString func(s string) string
+
+	// RuneSlicesAsString, if true, causes arrays and slices of runes
+	// to be printed as quoted strings; for example, "héllo".
+	// Since rune is an alias for int32, this includes []int32,
+	// but not slices of other types with int32 as their underlying type.
+	RuneSlicesAsString bool
+
+	// LineNumbers, if true, causes each line of output,
+	// including any LinePrefix, to be preceded by its number,
+	// zero-padded to the width of the last number, and "| ";
+	// for example, 07| Name: "x".
+	LineNumbers bool
+
+	// ShowEmbeddedInterfaces, if true, causes embedded interface fields
+	// to be printed even if they are nil, as <nil T> for the interface type T;
+	// for example, <nil io.Reader>.
+	// Embedded interfaces that are not nil are preceded by the interface type
+	// and the type of their value, as for ShowInterfaceType.
+	ShowEmbeddedInterfaces bool
+}
+
+// A MapOrder is an order in which map entries are printed.
+type MapOrder int
+
+const (
+	// ByKey orders map entries by key.
+	ByKey MapOrder = iota
+	// ByValue orders map entries by value, in the same way
+	// that ByKey orders keys, and entries with equal values by key.
+	ByValue
+)
+
+// delims returns the opening and closing delimiters
+// of a value of kind k: a struct, map, array, or slice.
+func (c *Config) delims(k reflect.Kind) (string, string) {
+	open, close := "[", "]"
+	switch k {
+	case reflect.Struct:
+		open, close = "{", "}"
+		if c.OpenStruct != "" {
+			open = c.OpenStruct
+		}
+		if c.CloseStruct != "" {
+			close = c.CloseStruct
+		}
+	case reflect.Map:
+		open, close = "{", "}"
+		if c.OpenMap != "" {
+			open = c.OpenMap
+		}
+		if c.CloseMap != "" {
+			close = c.CloseMap
+		}
+	default:
+		if c.OpenArray != "" {
+			open = c.OpenArray
+		}
+		if c.CloseArray != "" {
+			close = c.CloseArray
+		}
+	}
+	return open, close
+}
+
+// globalConfig returns a Config holding the package-level options.
+func globalConfig() Config {
+	return Config{
+		Indent:            Indent,
+		EmptyString:       EmptyString,
+		CyclePlaceholder:  CyclePlaceholder,
+		TruncationMarker:  TruncationMarker,
+		InlineUnder:       InlineUnder,
+		MaxOutputBytes:    MaxOutputBytes,
+		MaxStringLen:      MaxStringLen,
+		SyncMaps:          SyncMaps,
+		ShowUnexported:    ShowUnexported,
+		UseStringer:       UseStringer,
+		UseTextMarshaler:  UseTextMarshaler,
+		Summary:           Summary,
+		CanonicalFloats:   CanonicalFloats,
+		ShowTopType:       ShowTopType,
+		ShowNamedScalars:  ShowNamedScalars,
+		ShowIndirection:   ShowIndirection,
+		OmitZero:          OmitZero,
+		ShowInterfaceType: ShowInterfaceType,
+		MapKeysOnly:       MapKeysOnly,
+		Sparse:            Sparse,
+		BoolBitmap:        BoolBitmap,
+		ShowFieldTypes:    ShowFieldTypes,
+		FlattenEmbedded:   FlattenEmbedded,
+		Newline:           "\n",
+	}
+}
+
+// A Renderer prints values using the options of a Config.
+// Unlike the package-level functions, a Renderer
+// is not affected by changes to the package-level options.
+type Renderer struct {
+	cfg Config
+}
+
+// With returns a Renderer that prints values using cfg.
+func With(cfg Config) *Renderer {
+	if cfg.Indent == "" {
+		cfg.Indent = "\t"
+	}
+	if cfg.CyclePlaceholder == "" {
+		cfg.CyclePlaceholder = "<cycle>"
+	}
+	if cfg.TruncationMarker == "" {
+		cfg.TruncationMarker = "…"
+	}
+	if cfg.Newline == "" {
+		cfg.Newline = "\n"
+	}
+	return &Renderer{cfg: cfg}
+}
+
+// defaultRenderer returns a Renderer using the package-level options.
+// The options are used as they are,
+// so, for example, an empty Indent is not replaced.
+func defaultRenderer() *Renderer {
+	return &Renderer{cfg: globalConfig()}
+}
+
+// Fprint prints a pretty-looking version of a value to an io.Writer,
+// as described by the package-level Fprint.
+func (r *Renderer) Fprint(out io.Writer, v interface{}) error {
+	return r.FprintContext(context.Background(), out, v)
+}
+
+// FprintContext is like Fprint, but stops printing
+// if the context is cancelled or its deadline passes,
+// returning the context's error.
+func (r *Renderer) FprintContext(ctx context.Context, out io.Writer, v interface{}) error {
+	_, err := r.fprint(ctx, out, v, nil)
+	return err
+}
+
+// fprint prints the selection sel of v to out,
+// returning the number of values printed.
+func (r *Renderer) fprint(ctx context.Context, out io.Writer, v interface{}, sel *selection) (nodes int, err error) {
+	w := bufio.NewWriter(out)
+	done := false
+	defer func() {
+		// Write errors are returned by errWriter;
+		// a panic here comes from a PrettyPrint or String method.
+		// Before Go 1.21, recover returns nil after panic(nil),
+		// so done, not the recovered value, says whether there was a panic.
+		if e := recover(); e != nil || !done {
+			err = panicError(e)
+		}
+		if ferr := w.Flush(); err == nil {
+			err = ferr
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		done = true
+		return 0, err
+	}
+	cfg := &r.cfg
+	var tw io.Writer = w
+	if cfg.MaxOutputBytes > 0 {
+		// The limit counts all bytes written,
+		// so it applies outside of LinePrefix and LineNumbers.
+		tw = &truncWriter{w: w, n: cfg.MaxOutputBytes, marker: cfg.TruncationMarker}
+	}
+	lw := tw
+	var numbered *bytes.Buffer
+	if cfg.LineNumbers {
+		// The width of the numbers depends on the number of lines,
+		// so the output is numbered once it is complete.
+		numbered = bytes.NewBuffer(nil)
+		lw = numbered
+		if cfg.MaxOutputBytes > 0 {
+			// Numbering only adds bytes, so the text to number
+			// is cut short once it alone is over the limit.
+			lw = &capWriter{w: numbered, n: cfg.MaxOutputBytes}
+		}
+	}
+	// written counts the bytes written with their LinePrefix, for Summary.
+	written := &errWriter{w: lw}
+	lw = written
+	if cfg.LinePrefix != "" {
+		lw = &prefixWriter{w: lw, prefix: cfg.LinePrefix, bol: true}
+	}
+	p := &printer{cfg: cfg, ctx: ctx, t: &traversal{c: cfg}, out: &errWriter{w: lw}, path: make(map[identity]string), sel: sel}
+	if cfg.DedupPointers {
+		p.dedup = newDedup(p.t, valueOf(v), sel)
+	}
+	p.printTop(valueOf(v))
+	if p.out.err == nil {
+		// Small values are printed before the context is checked.
+		p.out.err = ctx.Err()
+	}
+	if cfg.Summary {
+		n := written.n
+		if numbered != nil {
+			// Each line will be numbered,
+			// including the line of the summary itself.
+			lines := bytes.Count(numbered.Bytes(), []byte(cfg.Newline)) + 1
+			n += lines * (len(strconv.Itoa(lines+1)) + len("| "))
+		}
+		p.pr("%s# %d nodes, %s", cfg.Newline, p.nodes, byteSize(n))
+	}
+	if numbered != nil {
+		if err := writeNumbered(tw, cfg.Newline, numbered.Bytes()); p.out.err == nil {
+			p.out.err = err
+		}
+	}
+	done = true
+	return p.nodes, p.out.err
+}
+
+// FprintAll prints pretty-looking versions of several values to an io.Writer,
+// each as by Fprint, separated by a line holding Separator.
+// It stops at the first error, returning it.
+func (r *Renderer) FprintAll(out io.Writer, vs ...interface{}) error {
+	sep := r.cfg.Newline + r.cfg.LinePrefix + r.cfg.Separator + r.cfg.Newline
+	for i, v := range vs {
+		if i > 0 {
+			if _, err := io.WriteString(out, sep); err != nil {
+				return err
+			}
+		}
+		if err := r.Fprint(out, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EstimateSize returns the number of bytes that Fprint would write
+// for a value, as described by the package-level EstimateSize.
+func (r *Renderer) EstimateSize(v interface{}) int {
+	var w byteCounter
+	r.fprint(context.Background(), &w, v, nil)
+	return int(w)
+}
+
+// A byteCounter counts the bytes written to it, discarding them.
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// writeNumbered writes text, whose lines end with newline, to w
+// with each line preceded by its number, as described by LineNumbers.
+func writeNumbered(w io.Writer, newline string, text []byte) error {
+	lines := bytes.SplitAfter(text, []byte(newline))
+	width := len(strconv.Itoa(len(lines)))
+	for i, line := range lines {
+		if _, err := fmt.Fprintf(w, "%0*d| %s", width, i+1, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Print prints a pretty-looking version of a value to os.Stdout.
+func (r *Renderer) Print(v interface{}) error {
+	return r.Fprint(os.Stdout, v)
+}
+
+// String prints a pretty-looking version of a value, returning it as a string.
+// String panics if Fprint returns an error; StringErr returns it instead.
+func (r *Renderer) String(v interface{}) string {
+	s, err := r.StringErr(v)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// StringErr is like String, but returns the error from Fprint, if any,
+// along with the output printed before the error,
+// rather than panicking.
+func (r *Renderer) StringErr(v interface{}) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	err := r.Fprint(buf, v)
+	return buf.String(), err
+}
+
+// Stat prints a pretty-looking version of a value, as by String,
+// returning it along with the number of values printed
+// and the length of the string in bytes.
+func (r *Renderer) Stat(v interface{}) (s string, nodes, bytes int) {
+	var buf strings.Builder
+	nodes, err := r.fprint(context.Background(), &buf, v, nil)
+	if err != nil {
+		panic(err)
+	}
+	return buf.String(), nodes, buf.Len()
+}
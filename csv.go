@@ -0,0 +1,127 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+)
+
+// CSV returns a value as a CSV table.
+// The value must be an array or slice of structs or of arrays or slices,
+// or pointers to them.
+//
+// For structs, the first row is a header of the names
+// of the fields that Fprint would print if they were not empty,
+// followed by a row for each struct.
+// For arrays or slices, there is no header, and each is a row.
+// Strings are written as they are, and other values
+// as Fprint prints them on a single line.
+// A nil pointer is written as a row of empty cells.
+//
+// CSV uses the package-level options.
+func CSV(v interface{}) (s string, err error) {
+	done := false
+	defer func() {
+		if r := recover(); r != nil || !done {
+			err = panicError(r)
+		}
+	}()
+	c := globalConfig()
+	rows, err := c.csvRows(valueOf(v))
+	if err != nil {
+		done = true
+		return "", err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.WriteAll(rows)
+	done = true
+	return buf.String(), w.Error()
+}
+
+// csvRows returns the rows of the CSV table for v.
+func (c *Config) csvRows(v reflect.Value) ([][]string, error) {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() != reflect.Array && v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("pretty: cannot print %s as CSV", typeString(v))
+	}
+	t := v.Type().Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		var header []string
+		var cols []int
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); c.visible(&f) {
+				header = append(header, f.Name)
+				cols = append(cols, i)
+			}
+		}
+		rows := [][]string{header}
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+				elem = elem.Elem()
+			}
+			row := make([]string, len(header))
+			if elem.Kind() == reflect.Struct {
+				// A copy is addressable, so its unexported fields can be read.
+				x := reflect.New(t).Elem()
+				x.Set(elem)
+				for j, i := range cols {
+					f, _ := c.field(x, i)
+					row[j] = c.csvCell(f)
+				}
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+
+	case reflect.Array, reflect.Slice:
+		var rows [][]string
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+				elem = elem.Elem()
+			}
+			var row []string
+			if elem.Kind() == reflect.Array || elem.Kind() == reflect.Slice {
+				for j := 0; j < elem.Len(); j++ {
+					row = append(row, c.csvCell(elem.Index(j)))
+				}
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+
+	default:
+		return nil, fmt.Errorf("pretty: cannot print %s as CSV", v.Type())
+	}
+}
+
+// csvCell returns the CSV cell for v.
+func (c *Config) csvCell(v reflect.Value) string {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		if _, ok := c.custom(v); ok {
+			break
+		}
+		v = v.Elem()
+	}
+	if _, ok := c.custom(v); !ok && v.Kind() == reflect.String {
+		return v.String()
+	}
+	return c.compactString(v)
+}
+
+// typeString returns the type of v, or nil if v is the zero Value.
+func typeString(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return v.Type().String()
+}
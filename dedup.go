@@ -0,0 +1,107 @@
+package pretty
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// dedup tracks the pointers shared within a value, for DedupPointers.
+type dedup struct {
+	// counts is the number of occurrences of each pointer.
+	counts map[identity]int
+	// ids is the number assigned to each shared pointer already printed.
+	ids map[identity]int
+}
+
+// newDedup counts the pointers within the selection sel of v.
+func newDedup(t *traversal, v reflect.Value, sel *selection) *dedup {
+	d := &dedup{counts: make(map[identity]int), ids: make(map[identity]int)}
+	pc := &pointerCounter{
+		t:      t,
+		d:      d,
+		root:   sel,
+		sels:   make(map[*node]*selection),
+		fields: make(map[*node]int),
+	}
+	t.traverse(pc, v)
+	return d
+}
+
+// A pointerCounter counts the occurrences of each non-nil pointer
+// that would be printed, not counting within a pointer's referent
+// after its first occurrence.
+// Like the printer, it skips map values if MapKeysOnly is true,
+// struct fields past MaxFields, and values not in the selection.
+type pointerCounter struct {
+	t *traversal
+	d *dedup
+	// root is the selection of the root value.
+	root *selection
+	// sels is the selection of each node being visited.
+	sels map[*node]*selection
+	// fields is the number of fields of each struct node visited so far.
+	fields map[*node]int
+}
+
+func (pc *pointerCounter) visit(n *node) bool {
+	sel, ok := pc.root, true
+	if p := n.parent; p != nil {
+		switch sel = pc.sels[p]; {
+		case p.kind == mapNode && !n.key && pc.t.c.MapKeysOnly:
+			return false
+		case p.kind == arrayNode:
+			sel, ok = sel.child("[" + strconv.Itoa(n.index) + "]")
+		case p.kind == structNode:
+			if sel, ok = sel.child(n.step.(string)); ok {
+				pc.fields[p]++
+				ok = pc.t.c.MaxFields <= 0 || pc.fields[p] <= pc.t.c.MaxFields
+			}
+		}
+	}
+	if !ok {
+		return false
+	}
+	if n.v.Kind() == reflect.Ptr && !n.v.IsNil() {
+		id, _ := identify(n.v)
+		if pc.d.counts[id]++; pc.d.counts[id] > 1 {
+			return false
+		}
+	}
+	if n.cycle {
+		return false
+	}
+	pc.sels[n] = sel
+	return true
+}
+
+func (pc *pointerCounter) leave(n *node) {
+	delete(pc.sels, n)
+	delete(pc.fields, n)
+}
+
+func (*pointerCounter) stopped() bool { return false }
+
+// id returns the number of the shared pointer v
+// and whether it was already printed.
+// If v is not a shared pointer, id returns 0.
+func (d *dedup) id(v reflect.Value) (int, bool) {
+	id, ok := identify(v)
+	if !ok || v.Kind() != reflect.Ptr || d.counts[id] < 2 {
+		return 0, false
+	}
+	if n, ok := d.ids[id]; ok {
+		return n, true
+	}
+	n := len(d.ids) + 1
+	d.ids[id] = n
+	return n, false
+}
+
+// clone returns a copy of d.
+func (d *dedup) clone() *dedup {
+	ids := make(map[identity]int, len(d.ids))
+	for id, n := range d.ids {
+		ids[id] = n
+	}
+	return &dedup{counts: d.counts, ids: ids}
+}
@@ -0,0 +1,71 @@
+package pretty
+
+import "strings"
+
+// Equal returns whether two values print identically.
+//
+// Equal compares the output of String,
+// so it shares its semantics with Fprint:
+// only fields that Fprint prints are compared,
+// cycles are pruned, and custom formatters, PrettyPrint methods,
+// and, if UseStringer is true, String methods are used.
+func Equal(a, b interface{}) bool {
+	return String(a) == String(b)
+}
+
+// Diff returns a line-by-line difference between the printed forms
+// of two values, or the empty string if they are Equal.
+//
+// Each line of the result is prefixed by "-" if it is only in a,
+// "+" if it is only in b, and " " if it is in both.
+func Diff(a, b interface{}) string {
+	return DiffText(String(a), String(b))
+}
+
+// DiffText returns a line-by-line difference between two strings,
+// in the same form as Diff, or the empty string if they are equal.
+func DiffText(a, b string) string {
+	if a == b {
+		return ""
+	}
+	return diffLines(strings.Split(a, "\n"), strings.Split(b, "\n"))
+}
+
+// diffLines returns the difference between a and b,
+// using their longest common subsequence.
+func diffLines(a, b []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var s strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			s.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			s.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			s.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return s.String()
+}
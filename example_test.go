@@ -1,6 +1,16 @@
 package pretty
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
 
 // Recall that if you pass a cyclic object by value then a copy is made.
 // The copy is not part of the cycle.
@@ -42,6 +52,24 @@ func ExamplePrint_prettyPrinter() {
 	// Output: <5, 6, 7>
 }
 
+type matrix [2][2]int
+
+func (m matrix) PrettyPrint() string {
+	return fmt.Sprintf("|%d %d|\n|%d %d|", m[0][0], m[0][1], m[1][0], m[1][1])
+}
+
+func ExamplePrint_multiLinePrettyPrinter() {
+	type Transform struct{ Scale matrix }
+	type Shape struct{ T Transform }
+	Print(Shape{T: Transform{Scale: matrix{{2, 0}, {0, 3}}}})
+	// Output: Shape{
+	// 	T: Transform{
+	// 		Scale: |2 0|
+	// 		       |0 3|
+	// 	}
+	// }
+}
+
 func ExamplePrint_emptyStruct() {
 	type T struct{}
 	Print(T{})
@@ -80,7 +108,7 @@ func ExamplePrint_nil() {
 
 func ExamplePrint_complex() {
 	Print(3 + 5i)
-	// Output: (3.000000+5.000000i)
+	// Output: 3.000000+5.000000i
 }
 
 func ExamplePrint_boolMap() {
@@ -180,3 +208,1275 @@ func ExamplePrint_indent() {
 	// ----C: 6
 	// }
 }
+
+// counter is a Visitor that counts the fields and scalars of a value.
+type counter struct {
+	fields, scalars, cycles int
+}
+
+func (c *counter) EnterStruct(reflect.Type) {}
+func (c *counter) EnterArray(reflect.Type)  {}
+func (c *counter) EnterMap(reflect.Type)    {}
+func (c *counter) Field(string)             { c.fields++ }
+func (c *counter) Key(reflect.Value)        {}
+func (c *counter) Scalar(reflect.Value)     { c.scalars++ }
+func (c *counter) Cycle(reflect.Value)      { c.cycles++ }
+func (c *counter) Leave()                   {}
+
+func ExampleWalk() {
+	type T struct {
+		A, b int
+		C    []string
+		D    *T
+	}
+	t := T{A: 1, C: []string{"x", "y"}}
+	t.D = &t
+	var c counter
+	Walk(&t, &c)
+	fmt.Println(c.fields, c.scalars, c.cycles)
+	// Output: 3 3 1
+}
+
+func ExampleRenderer_Walk() {
+	type T struct {
+		A, b int
+		C    []string
+		D    *T
+	}
+	t := T{A: 1, C: []string{"x", "y"}}
+	t.D = &t
+	var c counter
+	With(Config{ShowUnexported: true}).Walk(&t, &c)
+	fmt.Println(c.fields, c.scalars, c.cycles)
+	// Output: 4 4 1
+}
+
+func ExamplePrint_emptyString() {
+	type T struct{ A, B string }
+	orig := EmptyString
+	EmptyString = `""(empty)`
+	Print(T{B: "b"})
+	EmptyString = orig
+	// Output: T{
+	// 	A: ""(empty)
+	// 	B: "b"
+	// }
+}
+
+func ExamplePrint_showUnexported() {
+	type T struct {
+		A int
+		b string
+	}
+	orig := ShowUnexported
+	ShowUnexported = true
+	Print(&T{A: 5, b: "hidden"})
+	fmt.Println()
+	// Unexported fields of non-addressable values are not printed.
+	Print(T{A: 5, b: "hidden"})
+	ShowUnexported = orig
+	// Output: T{
+	// 	A: 5
+	// 	b: "hidden"
+	// }
+	// T{A: 5}
+}
+
+func ExamplePrint_maxStringLen() {
+	orig := MaxStringLen
+	MaxStringLen = 4
+	Print([]string{"abc", "abcdefgh", "αβγ"})
+	MaxStringLen = orig
+	// Output: [
+	// 	"abc"
+	// 	"abcd…" (+4 bytes)
+	// 	"αβ…" (+2 bytes)
+	// ]
+}
+
+func ExamplePrint_stringerSlice() {
+	orig := UseStringer
+	UseStringer = true
+	Print([]*big.Int{big.NewInt(1891284), nil, big.NewInt(-5)})
+	UseStringer = orig
+	// Output: [
+	// 	1891284
+	// 	nil
+	// 	-5
+	// ]
+}
+
+func ExamplePrint_stringerMap() {
+	orig := UseStringer
+	UseStringer = true
+	Print(map[string]*big.Int{
+		"a": big.NewInt(1),
+		"b": new(big.Int).Lsh(big.NewInt(1), 100),
+	})
+	UseStringer = orig
+	// Output: {
+	// 	"a": 1
+	// 	"b": 1267650600228229401496703205376
+	// }
+}
+
+func ExamplePrint_summary() {
+	type T struct {
+		A int
+		B []int
+	}
+	orig := Summary
+	Summary = true
+	Print(T{A: 5, B: []int{1, 2}})
+	Summary = orig
+	// Output: T{
+	// 	A: 5
+	// 	B: [
+	// 		1
+	// 		2
+	// 	]
+	// }
+	// # 5 nodes, 27B
+}
+
+func ExamplePrint_arrayMap() {
+	type T map[[2]int]string
+	Print(T{
+		{2, 1}: "c",
+		{1, 2}: "b",
+		{1, 1}: "a",
+	})
+	// Output: T{
+	// 	[1, 1]: "a"
+	// 	[1, 2]: "b"
+	// 	[2, 1]: "c"
+	// }
+}
+
+func ExamplePrint_structMap() {
+	type K struct{ X, Y int }
+	type T map[K]string
+	Print(T{
+		{X: 2, Y: 1}: "c",
+		{X: 1, Y: 2}: "b",
+		{X: 1, Y: 1}: "a",
+	})
+	// Output: T{
+	// 	K{X: 1, Y: 1}: "a"
+	// 	K{X: 1, Y: 2}: "b"
+	// 	K{X: 2, Y: 1}: "c"
+	// }
+}
+
+type celsius float64
+
+func (c celsius) String() string { return fmt.Sprintf("%.1f°C", float64(c)) }
+
+func ExamplePrint_rawTag() {
+	type T struct {
+		Temps []celsius
+		Raw   []celsius `pretty:"raw"`
+	}
+	orig := UseStringer
+	UseStringer = true
+	Print(T{Temps: []celsius{21.5}, Raw: []celsius{21.5}})
+	UseStringer = orig
+	// Output: T{
+	// 	Temps: [
+	// 		21.5°C
+	// 	]
+	// 	Raw: [
+	// 		21.500000
+	// 	]
+	// }
+}
+
+func ExamplePrint_interfaceMap() {
+	type K struct{ X int }
+	type T map[interface{}]int
+	Print(T{
+		"b":     1,
+		"a":     2,
+		2:       3,
+		1:       4,
+		int8(1): 5,
+		K{X: 1}: 6,
+		nil:     7,
+		true:    8,
+	})
+	// Output: T{
+	// 	nil: 7
+	// 	true: 8
+	// 	1: 4
+	// 	2: 3
+	// 	1: 5
+	// 	"a": 2
+	// 	"b": 1
+	// 	K{X: 1}: 6
+	// }
+}
+
+func ExampleSchema() {
+	type Point struct{ X, Y float64 }
+	type Node struct {
+		Name     string
+		Pos      Point
+		Tags     map[string][]int
+		Children []*Node
+		Parent   *Node
+		cache    []byte
+	}
+	fmt.Println(Schema(Node{}))
+	// Output: Node{
+	// 	Name: string
+	// 	Pos: Point{
+	// 		X: float64
+	// 		Y: float64
+	// 	}
+	// 	Tags: map[string][]int
+	// 	Children: []*Node
+	// 	Parent: *Node
+	// }
+}
+
+func ExamplePrint_showTopType() {
+	type List []int
+	type Celsius float64
+	orig := ShowTopType
+	ShowTopType = true
+	Print(List{1, 2})
+	fmt.Println()
+	Print(Celsius(37))
+	fmt.Println()
+	// Unnamed types are printed as usual.
+	Print([]int{3})
+	ShowTopType = orig
+	// Output: List[
+	// 	1
+	// 	2
+	// ]
+	// Celsius(37.000000)
+	// [
+	// 	3
+	// ]
+}
+
+func ExamplePrint_mapKeysOnly() {
+	type T map[string]int
+	orig := MapKeysOnly
+	MapKeysOnly = true
+	Print(T{"c": 3, "a": 1, "b": 2})
+	MapKeysOnly = orig
+	// Output: T{"a", "b", "c"}
+}
+
+func ExamplePrint_chan() {
+	type T struct {
+		In  <-chan int
+		Out chan<- string
+		Nil chan int
+	}
+	in := make(chan int, 8)
+	in <- 1
+	in <- 2
+	Print([]interface{}{T{In: in, Out: make(chan string)}, (chan int)(nil)})
+	// Output: [
+	// 	T{
+	// 		In: <-chan int (len 2, cap 8)
+	// 		Out: chan<- string (len 0, cap 0)
+	// 		Nil: nil
+	// 	}
+	// 	nil
+	// ]
+}
+
+func ExamplePrint_sparse() {
+	s := make([]int, 20)
+	s[0] = 4
+	s[2] = 5
+	s[19] = 6
+	orig := Sparse
+	Sparse = true
+	Print(s)
+	Sparse = orig
+	// Output: [
+	// 	0: 4
+	// 	… 1 zero …
+	// 	2: 5
+	// 	… 16 zeros …
+	// 	19: 6
+	// ]
+}
+
+func ExamplePrint_func() {
+	type T struct {
+		Handler func(int, string) error
+		Nil     func()
+	}
+	Print(T{Handler: func(int, string) error { return nil }})
+	// Output: T{
+	// 	Handler: func(int, string) error
+	// 	Nil: nil
+	// }
+}
+
+func ExamplePrint_truncationMarker() {
+	origMarker, origLen, origSparse := TruncationMarker, MaxStringLen, Sparse
+	TruncationMarker, MaxStringLen, Sparse = "...", 3, true
+	Print([]string{"abcdef", "", ""})
+	TruncationMarker, MaxStringLen, Sparse = origMarker, origLen, origSparse
+	// Output: [
+	// 	0: "abc..." (+3 bytes)
+	// 	... 2 zeros ...
+	// ]
+}
+
+func ExamplePrint_cyclePlaceholder() {
+	type T struct{ X *T }
+	var t T
+	t.X = &t
+	orig := CyclePlaceholder
+	CyclePlaceholder = "<recursive>"
+	Print(&t)
+	CyclePlaceholder = orig
+	// Output: T{
+	// 	X: <recursive>
+	// }
+}
+
+func ExamplePrint_syncMap() {
+	type T struct {
+		Cache *sync.Map
+		Empty sync.Map
+	}
+	var m sync.Map
+	m.Store("b", 2)
+	m.Store("a", 1)
+	orig := SyncMaps
+	SyncMaps = true
+	Print(&T{Cache: &m})
+	SyncMaps = orig
+	// Output: T{
+	// 	Cache: sync.Map{
+	// 		"a": 1
+	// 		"b": 2
+	// 	}
+	// 	Empty: sync.Map{}
+	// }
+}
+
+func ExamplePrint_inlineUnder() {
+	type Point struct{ X, Y int }
+	type Line struct {
+		Name       string
+		Start, End Point
+		Tags       []string
+	}
+	orig := InlineUnder
+	InlineUnder = 20
+	Print(Line{
+		Name:  "diagonal",
+		Start: Point{X: 1, Y: 2},
+		End:   Point{X: 10, Y: 20},
+		Tags:  []string{"a", "b"},
+	})
+	InlineUnder = orig
+	// Output: Line{
+	// 	Name: "diagonal"
+	// 	Start: Point{X: 1, Y: 2}
+	// 	End: Point{X: 10, Y: 20}
+	// 	Tags: ["a", "b"]
+	// }
+}
+
+func ExamplePrint_showNamedScalars() {
+	type Celsius float64
+	type T struct {
+		Temp  Celsius
+		Count int
+	}
+	orig := ShowNamedScalars
+	ShowNamedScalars = true
+	Print(T{Temp: 37, Count: 2})
+	ShowNamedScalars = orig
+	// Output: T{
+	// 	Temp: Celsius(37.000000)
+	// 	Count: 2
+	// }
+}
+
+func ExamplePrint_showIndirection() {
+	type T struct{ A int }
+	type U struct {
+		P  *T
+		PP **T
+		N  **T
+	}
+	t := &T{A: 1}
+	var nilT *T
+	orig := ShowIndirection
+	ShowIndirection = true
+	Print(U{P: t, PP: &t, N: &nilT})
+	ShowIndirection = orig
+	// Output: U{
+	// 	P: &T{A: 1}
+	// 	PP: &&T{A: 1}
+	// 	N: &nil
+	// }
+}
+
+func ExampleEqual() {
+	type T struct {
+		A     int
+		cache []int
+	}
+	fmt.Println(Equal(T{A: 1, cache: []int{1}}, T{A: 1}))
+	fmt.Println(Equal(T{A: 1}, T{A: 2}))
+	// Output: true
+	// false
+}
+
+func ExampleDiff() {
+	type T struct{ A, B, C int }
+	fmt.Print(Diff(T{A: 1, B: 2, C: 3}, T{A: 1, B: 5, C: 3}))
+	// Output:  T{
+	//  	A: 1
+	// -	B: 2
+	// +	B: 5
+	//  	C: 3
+	//  }
+}
+
+func ExamplePrint_reflectValue() {
+	type T struct {
+		A int
+		b []string
+	}
+	v := reflect.ValueOf(T{A: 1, b: []string{"x", "y"}})
+	Print(v)
+	fmt.Println()
+	// Values of unexported fields can be printed, too.
+	Print(v.Field(1))
+	// Output: T{A: 1}
+	// [
+	// 	"x"
+	// 	"y"
+	// ]
+}
+
+func ExamplePrint_big() {
+	type T struct {
+		Int   *big.Int
+		Rat   *big.Rat
+		Float *big.Float
+	}
+	orig := UseStringer
+	UseStringer = true
+	Print(T{
+		Int:   big.NewInt(1891284),
+		Rat:   big.NewRat(3, 4),
+		Float: big.NewFloat(1.5),
+	})
+	UseStringer = orig
+	// Output: T{
+	// 	Int: 1891284
+	// 	Rat: 3/4
+	// 	Float: 1.5
+	// }
+}
+
+func ExamplePrint_omitZero() {
+	type Config struct {
+		Name    string
+		Port    int
+		Debug   bool
+		Retries int
+	}
+	orig := OmitZero
+	OmitZero = true
+	Print(Config{Name: "server", Port: 8080})
+	fmt.Println()
+	Print(Config{})
+	OmitZero = orig
+	// Output: Config{
+	// 	Name: "server"
+	// 	Port: 8080
+	// 	…
+	// }
+	// Config{…}
+}
+
+func ExamplePrint_showInterfaceType() {
+	type Lit struct{ Value int }
+	type Node struct {
+		Left, Right interface{}
+		Op          fmt.Stringer
+	}
+	orig, origStringer := ShowInterfaceType, UseStringer
+	ShowInterfaceType, UseStringer = true, true
+	Print(Node{Left: &Lit{Value: 1}, Right: 2, Op: celsius(3)})
+	ShowInterfaceType, UseStringer = orig, origStringer
+	// Output: Node{
+	// 	Left: interface {}(*pretty.Lit) Lit{Value: 1}
+	// 	Right: interface {}(int) 2
+	// 	Op: fmt.Stringer(pretty.celsius) 3.0°C
+	// }
+}
+
+func ExampleLabeled() {
+	type T struct{ A, B int }
+	fmt.Println(Labeled("request", T{A: 1, B: 2}))
+	// Output: request: T{
+	//          	A: 1
+	//          	B: 2
+	//          }
+}
+
+func ExamplePrint_selfSlice() {
+	s := []interface{}{1, nil}
+	s[1] = s
+	Print(s)
+	// Output: [
+	// 	1
+	// 	<cycle>
+	// ]
+}
+
+func ExamplePrint_selfMap() {
+	m := map[string]interface{}{"a": 1}
+	m["self"] = m
+	Print(m)
+	// Output: {
+	// 	"a": 1
+	// 	"self": <cycle>
+	// }
+}
+
+// Distinct containers with equal contents are not cycles.
+func ExamplePrint_equalSlices() {
+	inner := []interface{}{1}
+	outer := []interface{}{1, inner}
+	Print(outer)
+	// Output: [
+	// 	1
+	// 	[
+	// 		1
+	// 	]
+	// ]
+}
+
+func ExamplePrint_showFieldTypes() {
+	type Job struct {
+		Name    string
+		Retries int
+		Timeout time.Duration
+		Tags    []string
+	}
+	orig := ShowFieldTypes
+	ShowFieldTypes = true
+	Print(Job{Name: "backup", Retries: 5, Timeout: 5, Tags: []string{"nightly"}})
+	ShowFieldTypes = orig
+	// Output: Job{
+	// 	Name string: "backup"
+	// 	Retries int: 5
+	// 	Timeout time.Duration: 5
+	// 	Tags []string: [
+	// 		"nightly"
+	// 	]
+	// }
+}
+
+func ExamplePrint_flattenEmbedded() {
+	type Base struct {
+		ID   int
+		Name string
+	}
+	type User struct {
+		Base
+		Name  string
+		Email string
+	}
+	orig := FlattenEmbedded
+	FlattenEmbedded = true
+	Print(User{Base: Base{ID: 7, Name: "base"}, Name: "ann", Email: "ann@example.com"})
+	FlattenEmbedded = orig
+	// Output: User{
+	// 	ID: 7
+	// 	Base.Name: "base"
+	// 	Name: "ann"
+	// 	Email: "ann@example.com"
+	// }
+}
+
+func ExamplePrint_nilSlice() {
+	var ids []int
+	Print(map[string][]int{"nil": ids, "empty": {}, "full": {1}})
+	// Output: {
+	// 	"empty": []
+	// 	"full": [
+	// 		1
+	// 	]
+	// 	"nil": nil
+	// }
+}
+
+// level fails to marshal values out of range,
+// which are printed by reflection instead.
+type level int
+
+func (l level) MarshalText() ([]byte, error) {
+	if l < 0 || l > 2 {
+		return nil, fmt.Errorf("bad level %d", int(l))
+	}
+	return []byte([]string{"low", "mid", "high"}[l]), nil
+}
+
+func ExamplePrint_useTextMarshaler() {
+	type Host struct {
+		Addr   net.IP
+		Levels []level
+	}
+	orig := UseTextMarshaler
+	UseTextMarshaler = true
+	Print(Host{Addr: net.IPv4(10, 0, 0, 1), Levels: []level{2, 7}})
+	UseTextMarshaler = orig
+	// Output: Host{
+	// 	Addr: "10.0.0.1"
+	// 	Levels: [
+	// 		"high"
+	// 		7
+	// 	]
+	// }
+}
+
+func ExamplePrint_boolBitmap() {
+	type Features struct {
+		Enabled [4]bool
+		Seen    []bool
+	}
+	orig := BoolBitmap
+	BoolBitmap = true
+	Print(Features{Enabled: [4]bool{true, false, true, true}, Seen: []bool{false, true}})
+	BoolBitmap = orig
+	// Output: Features{
+	// 	Enabled: [1 0 1 1]
+	// 	Seen: [0 1]
+	// }
+}
+
+func ExampleWith() {
+	type Point struct{ X, Y int }
+	r := With(Config{Indent: "  ", InlineUnder: 20})
+	r.Print([]Point{{1, 2}, {3, 4}, {5, 6}})
+	// Output: [
+	//   Point{X: 1, Y: 2}
+	//   Point{X: 3, Y: 4}
+	//   Point{X: 5, Y: 6}
+	// ]
+}
+
+func ExampleConfig_dedupPointers() {
+	type Node struct {
+		Name     string
+		Children []*Node
+	}
+	leaf := &Node{Name: "leaf"}
+	root := &Node{Name: "root", Children: []*Node{
+		{Name: "a", Children: []*Node{leaf}},
+		{Name: "b", Children: []*Node{leaf}},
+	}}
+	With(Config{DedupPointers: true}).Print(root)
+	// Output: Node{
+	// 	Name: "root"
+	// 	Children: [
+	// 		Node{
+	// 			Name: "a"
+	// 			Children: [
+	// 				#1 Node{Name: "leaf"}
+	// 			]
+	// 		}
+	// 		Node{
+	// 			Name: "b"
+	// 			Children: [
+	// 				↺ #1
+	// 			]
+	// 		}
+	// 	]
+	// }
+}
+
+func ExampleConfig_linePrefix() {
+	type Request struct {
+		Method, Path string
+	}
+	With(Config{LinePrefix: "req| "}).Print(Request{Method: "GET", Path: "/"})
+	// Output: req| Request{
+	// req| 	Method: "GET"
+	// req| 	Path: "/"
+	// req| }
+}
+
+func ExampleConfig_fieldVisible() {
+	type Account struct {
+		User     string
+		Password string `log:"-"`
+		balance  int
+	}
+	r := With(Config{FieldVisible: func(f reflect.StructField) bool {
+		return f.Tag.Get("log") != "-"
+	}})
+	r.Print(&Account{User: "ann", Password: "hunter2", balance: 10})
+	// Output: Account{
+	// 	User: "ann"
+	// 	balance: 10
+	// }
+}
+
+func ExampleConfig_floatFormat() {
+	r := With(Config{FloatFormat: "%g"})
+	r.Print([]complex128{3 + 5i, 5i, 3, 3 - 5i, 0})
+	fmt.Println()
+	r.Print(2.5)
+	// Output: [
+	// 	3+5i
+	// 	5i
+	// 	3+0i
+	// 	3-5i
+	// 	0i
+	// ]
+	// 2.5
+}
+
+func ExampleConfig_alignFields() {
+	type Server struct {
+		Host        string
+		Port        int
+		ReadTimeout float64
+	}
+	With(Config{AlignFields: true}).Print(Server{Host: "localhost", Port: 8080, ReadTimeout: 2.5})
+	// Output: Server{
+	// 	Host:        "localhost"
+	// 	Port:        8080
+	// 	ReadTimeout: 2.500000
+	// }
+}
+
+func ExampleConfig_delimiters() {
+	type Call struct {
+		Fn   string
+		Args []int
+	}
+	type Empty struct{}
+	r := With(Config{OpenStruct: "(", CloseStruct: ")", OpenArray: "<", CloseArray: ">"})
+	r.Print([]interface{}{Call{Fn: "max", Args: []int{1, 2}}, Empty{}, []int{}})
+	// Output: <
+	// 	Call(
+	// 		Fn: "max"
+	// 		Args: <
+	// 			1
+	// 			2
+	// 		>
+	// 	)
+	// 	Empty()
+	// 	<>
+	// >
+}
+
+func ExampleConfig_mapSort() {
+	counts := map[string]int{"get": 120, "put": 7, "delete": 7, "post": 31}
+	With(Config{MapSort: ByValue}).Print(counts)
+	// Output: {
+	// 	"delete": 7
+	// 	"put": 7
+	// 	"post": 31
+	// 	"get": 120
+	// }
+}
+
+func ExampleStat() {
+	type Point struct{ X, Y int }
+	s, nodes, bytes := Stat([]Point{{1, 2}, {3, 4}})
+	fmt.Println(s)
+	fmt.Println(nodes, "nodes,", bytes, "bytes")
+	// Output: [
+	// 	Point{
+	// 		X: 1
+	// 		Y: 2
+	// 	}
+	// 	Point{
+	// 		X: 3
+	// 		Y: 4
+	// 	}
+	// ]
+	// 7 nodes, 53 bytes
+}
+
+func ExamplePrintPath() {
+	type Point struct{ X, Y int }
+	type Shape struct {
+		Name   string
+		Center Point
+		Points []Point
+	}
+	s := Shape{
+		Name:   "triangle",
+		Center: Point{X: 1, Y: 1},
+		Points: []Point{{0, 0}, {2, 0}, {1, 2}},
+	}
+	PrintPath(s, "Center.Y", "Points[2]")
+	// Output: Shape{
+	// 	Center: Point{
+	// 		Y: 1
+	// 		…
+	// 	}
+	// 	Points: [
+	// 		2: Point{
+	// 			X: 1
+	// 			Y: 2
+	// 		}
+	// 		…
+	// 	]
+	// 	…
+	// }
+}
+
+// account hides its state behind methods.
+type account struct{ balance int }
+
+func (a *account) Balance() int  { return a.balance }
+func (a *account) Owner() string { return "ann" }
+func (a *account) Limit() int    { panic("no limit") }
+func (a *account) Deposit(n int) { a.balance += n }
+
+func ExampleConfig_callGetters() {
+	type Wallet struct{ Account *account }
+	With(Config{CallGetters: true}).Print(Wallet{Account: &account{balance: 10}})
+	// Output: Wallet{
+	// 	Account: account{
+	// 		Balance() -> 10
+	// 		Limit() -> <error>
+	// 		Owner() -> "ann"
+	// 	}
+	// }
+}
+
+func ExampleConfig_skipKinds() {
+	type Handler struct {
+		Name    string
+		Serve   func()
+		Done    chan bool
+		Timeout int
+	}
+	r := With(Config{SkipKinds: []reflect.Kind{reflect.Func, reflect.Chan}})
+	r.Print(Handler{Name: "api", Serve: func() {}, Done: make(chan bool), Timeout: 30})
+	// Output: Handler{
+	// 	Name: "api"
+	// 	Timeout: 30
+	// 	…
+	// }
+}
+
+func ExampleConfig_verboseCycles() {
+	type Node struct {
+		Name string
+		Next *Node
+	}
+	type List struct{ Nodes []*Node }
+	a := &Node{Name: "a"}
+	b := &Node{Name: "b", Next: a}
+	a.Next = b
+	With(Config{VerboseCycles: true}).Print(List{Nodes: []*Node{a}})
+	// Output: List{
+	// 	Nodes: [
+	// 		Node{
+	// 			Name: "a"
+	// 			Next: Node{
+	// 				Name: "b"
+	// 				Next: <cycle -> /Nodes/0>
+	// 			}
+	// 		}
+	// 	]
+	// }
+}
+
+func ExampleConfig_inlineScalarMaps() {
+	type Stats struct {
+		Counts map[string]int
+		Groups map[string][]string
+	}
+	With(Config{InlineScalarMaps: true}).Print(Stats{
+		Counts: map[string]int{"b": 6, "a": 5},
+		Groups: map[string][]string{"x": {"y", "z"}},
+	})
+	// Output: Stats{
+	// 	Counts: {"a": 5, "b": 6}
+	// 	Groups: {
+	// 		"x": [
+	// 			"y"
+	// 			"z"
+	// 		]
+	// 	}
+	// }
+}
+
+func ExampleConfig_describeIO() {
+	type Job struct {
+		In  io.Reader
+		Out io.Writer
+		Log io.Writer
+	}
+	With(Config{DescribeIO: true}).Print(Job{
+		In:  os.Stdin,
+		Out: bytes.NewBufferString("hello"),
+	})
+	// Output: Job{
+	// 	In: <*os.File "/dev/stdin">
+	// 	Out: <*bytes.Buffer len=5>
+	// }
+}
+
+func ExampleConfig_matrixLayout() {
+	With(Config{MatrixLayout: true}).Print([]interface{}{
+		[][]int{{1, 2, 300}, {40, -5, 6}},
+		[][]int{{1, 2}, {3}},
+	})
+	// Output: [
+	// 	[
+	// 		[ 1  2 300]
+	// 		[40 -5   6]
+	// 	]
+	// 	[
+	// 		[
+	// 			1
+	// 			2
+	// 		]
+	// 		[
+	// 			3
+	// 		]
+	// 	]
+	// ]
+}
+
+func ExampleFprintAll() {
+	type Point struct{ X, Y int }
+	FprintAll(os.Stdout, Point{X: 1}, []int{2, 3})
+	fmt.Println()
+	With(Config{Separator: "---"}).FprintAll(os.Stdout, "a", "b")
+	// Output: Point{
+	// 	X: 1
+	// 	Y: 0
+	// }
+	//
+	// [
+	// 	2
+	// 	3
+	// ]
+	// "a"
+	// ---
+	// "b"
+}
+
+func ExampleConfig_runesAsChars() {
+	type Token struct {
+		Delim  rune   `pretty:"rune"`
+		Quotes []rune `pretty:"rune"`
+		Count  int32
+	}
+	With(Config{RunesAsChars: true}).Print(Token{Delim: ',', Quotes: []rune{'"', 'α', '«'}, Count: 3})
+	// Output: Token{
+	// 	Delim: ','
+	// 	Quotes: [
+	// 		'"'
+	// 		'α'
+	// 		'«'
+	// 	]
+	// 	Count: 3
+	// }
+}
+
+func ExampleConfig_maxFields() {
+	type Options struct {
+		Name    string
+		Verbose bool
+		Retries int
+		Timeout time.Duration
+		Region  string
+	}
+	With(Config{MaxFields: 2}).Print(Options{Name: "job", Retries: 3})
+	// Output: Options{
+	// 	Name: "job"
+	// 	Verbose: false
+	// 	… (+3 more)
+	// }
+}
+
+func ExampleRegisterEnum() {
+	type Color int
+	type Light struct{ Now, Next, Broken Color }
+	RegisterEnum(reflect.TypeOf(Color(0)), map[int64]string{0: "Red", 1: "Green", 2: "Yellow"})
+	defer RegisterEnum(reflect.TypeOf(Color(0)), nil)
+
+	v := Light{Now: 1, Next: 2, Broken: 7}
+	Print(v)
+	fmt.Println()
+	With(Config{ShowNamedScalars: true}).Print(v)
+	// Output: Light{
+	// 	Now: Green
+	// 	Next: Yellow
+	// 	Broken: 7
+	// }
+	// Light{
+	// 	Now: Color(Green)
+	// 	Next: Color(Yellow)
+	// 	Broken: Color(7)
+	// }
+}
+
+func ExampleConfig_wrapWidth() {
+	type Doc struct {
+		Title string
+		Body  string
+	}
+	With(Config{WrapWidth: 40, Indent: "  "}).Print(Doc{
+		Title: "Notes",
+		Body:  "Long strings are split at spaces into pieces that each fit within the width.",
+	})
+	// Output: Doc{
+	//   Title: "Notes"
+	//   Body: "Long strings are split at " +
+	//     "spaces into pieces that each " +
+	//     "fit within the width."
+	// }
+}
+
+func ExampleCSV() {
+	type Point struct{ X, Y int }
+	type Row struct {
+		Name  string
+		Score float64
+		At    Point
+		Tags  []string
+	}
+	s, _ := CSV([]Row{
+		{Name: "ann", Score: 9.5, At: Point{X: 1, Y: 2}, Tags: []string{"a", "b"}},
+		{Name: "bob, jr.", Score: 7},
+	})
+	fmt.Print(s)
+	s, _ = CSV([][]int{{1, 2, 3}, {4, 5, 6}})
+	fmt.Print(s)
+	// Output: Name,Score,At,Tags
+	// ann,9.500000,"Point{X: 1, Y: 2}","[""a"", ""b""]"
+	// "bob, jr.",7.000000,"Point{X: 0, Y: 0}",nil
+	// 1,2,3
+	// 4,5,6
+}
+
+func ExampleConfig_width() {
+	type Point struct{ X, Y int }
+	type Shape struct {
+		Name   string
+		Points []Point
+		Tags   map[string]int
+	}
+	With(Config{Width: 32, Indent: "  "}).Print([]Shape{
+		{Name: "dot", Points: []Point{{X: 1, Y: 2}}},
+		{
+			Name:   "triangle",
+			Points: []Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 0, Y: 3}},
+			Tags:   map[string]int{"sides": 3},
+		},
+	})
+	// Output: [
+	//   Shape{
+	//     Name: "dot"
+	//     Points: [Point{X: 1, Y: 2}]
+	//   }
+	//   Shape{
+	//     Name: "triangle"
+	//     Points: [
+	//       Point{X: 0, Y: 0}
+	//       Point{X: 4, Y: 0}
+	//       Point{X: 0, Y: 3}
+	//     ]
+	//     Tags: {"sides": 3}
+	//   }
+	// ]
+}
+
+func ExampleConfig_showLenCap() {
+	type Buffer struct {
+		Data   []byte
+		Header [2]int
+	}
+	r := With(Config{ShowLenCap: true, InlineUnder: 20})
+	r.Print(Buffer{Data: make([]byte, 3, 8), Header: [2]int{1, 2}})
+	fmt.Println()
+	r.Print(make([]int, 0, 4))
+	// Output: Buffer{
+	// 	Data: []uint8(len 3, cap 8)[0, 0, 0]
+	// 	Header: [2]int(len 2)[1, 2]
+	// }
+	// []int(len 0, cap 4)[]
+}
+
+type parseError struct{ Line int }
+
+func (e *parseError) Error() string { return fmt.Sprintf("line %d", e.Line) }
+
+// A nil interface prints as nil,
+// but an interface holding a nil pointer prints as the pointer's type and nil.
+func ExamplePrint_nilInterface() {
+	type Result struct {
+		Err  error
+		Errs []error
+	}
+	var pe *parseError
+	Print(Result{Err: pe, Errs: []error{nil, pe, &parseError{Line: 3}}})
+	// Output: Result{
+	// 	Err: (*pretty.parseError)(nil)
+	// 	Errs: [
+	// 		nil
+	// 		(*pretty.parseError)(nil)
+	// 		parseError{Line: 3}
+	// 	]
+	// }
+}
+
+func ExampleRegisterFlags() {
+	type Perm uint8
+	type File struct{ Owner, Group, Other Perm }
+	RegisterFlags(reflect.TypeOf(Perm(0)), map[int64]string{0: "None", 1: "Exec", 2: "Write", 4: "Read"})
+	defer RegisterFlags(reflect.TypeOf(Perm(0)), nil)
+
+	v := File{Owner: 7, Group: 5, Other: 0}
+	Print(v)
+	fmt.Println()
+	With(Config{ShowNamedScalars: true}).Print([]Perm{4, 0x14})
+	// Output: File{
+	// 	Owner: Exec|Write|Read
+	// 	Group: Exec|Read
+	// 	Other: None
+	// }
+	// [
+	// 	Perm(Read)
+	// 	Perm(Read|0x10)
+	// ]
+}
+
+func ExampleConfig_runeSlicesAsString() {
+	type Scanner struct {
+		Text  []rune
+		Ahead [3]rune
+		Lines []int
+	}
+	With(Config{RuneSlicesAsString: true}).Print(Scanner{
+		Text:  []rune("naïve → 日本"),
+		Ahead: [3]rune{'a', 'β', 0},
+		Lines: []int{1},
+	})
+	// Output: Scanner{
+	// 	Text: "naïve → 日本"
+	// 	Ahead: "aβ\x00"
+	// 	Lines: [
+	// 		1
+	// 	]
+	// }
+}
+
+// isPayload is the interface of a oneof field,
+// as generated for protocol buffers.
+type isPayload interface{ isPayload() }
+
+type Click struct{ X, Y int }
+type KeyPress struct{ Key *Key }
+type Key struct {
+	Code  int
+	Shift bool
+}
+type Delay int
+
+func (*Click) isPayload()    {}
+func (*KeyPress) isPayload() {}
+func (Delay) isPayload()     {}
+
+func ExampleRegisterUnion() {
+	type Event struct {
+		ID      int
+		Payload isPayload
+	}
+	RegisterUnion(reflect.TypeOf((*isPayload)(nil)).Elem(), true)
+	defer RegisterUnion(reflect.TypeOf((*isPayload)(nil)).Elem(), false)
+	Print([]Event{
+		{ID: 1, Payload: &Click{X: 3, Y: 4}},
+		{ID: 2, Payload: &KeyPress{Key: &Key{Code: 65, Shift: true}}},
+		{ID: 3, Payload: Delay(10)},
+		{ID: 4},
+	})
+	// Output: [
+	// 	Event{
+	// 		ID: 1
+	// 		Payload = Click{X: 3, Y: 4}
+	// 	}
+	// 	Event{
+	// 		ID: 2
+	// 		Payload = KeyPress{Key: Key{Code: 65, Shift: true}}
+	// 	}
+	// 	Event{
+	// 		ID: 3
+	// 		Payload = Delay(10)
+	// 	}
+	// 	Event{ID: 4}
+	// ]
+}
+
+func ExampleConfig_lineNumbers() {
+	type Point struct{ X, Y int }
+	type Path struct {
+		Name   string
+		Points []Point
+	}
+	With(Config{LineNumbers: true}).Print(Path{
+		Name:   "zigzag",
+		Points: []Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 0}},
+	})
+	// Output: 01| Path{
+	// 02| 	Name: "zigzag"
+	// 03| 	Points: [
+	// 04| 		Point{
+	// 05| 			X: 0
+	// 06| 			Y: 0
+	// 07| 		}
+	// 08| 		Point{
+	// 09| 			X: 1
+	// 10| 			Y: 1
+	// 11| 		}
+	// 12| 		Point{
+	// 13| 			X: 2
+	// 14| 			Y: 0
+	// 15| 		}
+	// 16| 	]
+	// 17| }
+}
+
+type Box[T any] struct{ Value T }
+
+type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+func ExamplePrint_generic() {
+	Print([]interface{}{
+		Box[int]{Value: 1},
+		Pair[string, Box[bool]]{Key: "ok", Val: Box[bool]{Value: true}},
+		struct{ A int }{A: 2},
+	})
+	// Output: [
+	// 	Box[int]{Value: 1}
+	// 	Pair[string,pretty.Box[bool]]{
+	// 		Key: "ok"
+	// 		Val: Box[bool]{Value: true}
+	// 	}
+	// 	struct { A int }{A: 2}
+	// ]
+}
@@ -0,0 +1,117 @@
+package pretty
+
+import "reflect"
+
+// A getter is the result of calling a method for CallGetters.
+type getter struct {
+	name string
+	v    reflect.Value
+	// ok is false if the method panicked.
+	ok bool
+}
+
+// callGetters calls the exported methods of v that take no arguments
+// and return a single value, in the order of their names.
+// If v is addressable, the methods of its pointer are called.
+func callGetters(v reflect.Value) []getter {
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+	var gs []getter
+	t := v.Type()
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		// The receiver is the first argument.
+		if m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+			continue
+		}
+		g := getter{name: m.Name}
+		g.v, g.ok = call(v.Method(i))
+		gs = append(gs, g)
+	}
+	return gs
+}
+
+// callGetters returns the results of the getters of v, as by callGetters,
+// calling them only the first time they are needed for v.
+func (t *traversal) callGetters(v reflect.Value) []getter {
+	k, ok := getterKey(v)
+	if !ok {
+		return callGetters(v)
+	}
+	if gs, ok := t.getters[k]; ok {
+		return gs
+	}
+	if t.getters == nil {
+		t.getters = make(map[interface{}][]getter)
+	}
+	gs := callGetters(v)
+	t.getters[k] = gs
+	return gs
+}
+
+// getterKey returns the key of the results of v's getters in a cache
+// and whether there is one:
+// the address of v, if it is addressable,
+// or else v itself, if its type can be used as a map key
+// without risk of a panic.
+func getterKey(v reflect.Value) (interface{}, bool) {
+	if v.CanAddr() {
+		return identity{t: v.Type(), p: v.Addr().Pointer()}, true
+	}
+	if !hashable(v.Type()) {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+// hashable returns whether every value of type t can be a map key.
+// Interfaces are not, since they may hold a value that cannot.
+func hashable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+		return false
+	case reflect.Array:
+		return hashable(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !hashable(t.Field(i).Type) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// call calls the method m, returning its result
+// and false if it panicked.
+func call(m reflect.Value) (v reflect.Value, ok bool) {
+	// The results stay zero if m panics.
+	defer func() { recover() }()
+	return m.Call(nil)[0], true
+}
+
+// printGetters prints the struct v as the results of its getters.
+func (p *printer) printGetters(indent string, v reflect.Value, gs []getter) {
+	if p.printInline(func(q *printer) { q.printGetters(indent, v, gs) }) {
+		return
+	}
+	open, close := p.cfg.delims(reflect.Struct)
+	p.pr("%s%s", structName(v.Type()), open)
+	indent2 := indent + p.cfg.Indent
+	inGetter := p.inGetter
+	p.inGetter = true
+	for i, g := range gs {
+		p.line(indent2, i)
+		p.pr("%s() -> ", g.name)
+		if g.ok {
+			p.push(g.name + "()")
+			p.print(indent2, g.v)
+			p.pop()
+		} else {
+			p.pr("<error>")
+		}
+	}
+	p.inGetter = inGetter
+	p.end(indent, close)
+}
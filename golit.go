@@ -0,0 +1,205 @@
+package pretty
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// FprintGo prints a value to an io.Writer as a Go literal expression.
+//
+// Unlike Fprint, the output is meant to compile:
+// composite literals carry their full type, from reflect.Type.String,
+// including those nested within other composites,
+// and numeric values held in interfaces are converted to their type.
+// Unexported struct fields are omitted.
+//
+// Non-nil channels, functions, and unsafe pointers, and cycles,
+// have no literal form; FprintGo returns an error if it encounters them.
+func FprintGo(out io.Writer, v interface{}) error {
+	c := globalConfig()
+	g := &goPrinter{out: &errWriter{w: out}}
+	(&traversal{c: &c, literal: true}).traverse(g, reflect.ValueOf(v))
+	return g.out.err
+}
+
+// GoString prints a value as a Go literal expression, returning it as a string.
+func GoString(v interface{}) string {
+	buf := bytes.NewBuffer(nil)
+	if err := FprintGo(buf, v); err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+// A goPrinter prints Go literals for the nodes of a traversal, for FprintGo.
+type goPrinter struct {
+	out *errWriter
+}
+
+// pr prints to g.out.
+// Write errors are recorded by g.out, which ignores later writes.
+func (g *goPrinter) pr(f string, args ...interface{}) {
+	fmt.Fprintf(g.out, f, args...)
+}
+
+func (g *goPrinter) visit(n *node) bool {
+	if p := n.parent; p != nil && p.kind != refNode {
+		switch {
+		case p.kind == mapNode && !n.key:
+			g.pr(": ")
+		case n.index > 0:
+			g.pr(", ")
+		}
+		if p.kind == structNode {
+			g.pr("%s: ", n.step)
+		}
+	}
+	v := n.v
+	if n.cycle {
+		g.out.err = errors.New("pretty: cannot print a cycle as a Go literal")
+		return false
+	}
+	if !v.IsValid() {
+		g.pr("nil")
+		return false
+	}
+	iface := g.iface(n)
+
+	switch v.Kind() {
+	case reflect.Bool:
+		g.printScalar(iface, v, strconv.FormatBool(v.Bool()))
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		g.printScalar(iface, v, strconv.FormatInt(v.Int(), 10))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		g.printScalar(iface, v, strconv.FormatUint(v.Uint(), 10))
+
+	case reflect.Float32, reflect.Float64:
+		bits := v.Type().Bits()
+		g.printScalar(iface, v, strconv.FormatFloat(v.Float(), 'g', -1, bits))
+
+	case reflect.Complex64, reflect.Complex128:
+		g.printScalar(iface, v, fmt.Sprintf("%g", v.Complex()))
+
+	case reflect.String:
+		g.printScalar(iface, v, strconv.Quote(v.String()))
+
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.Struct:
+		if v.Kind() != reflect.Array && v.Kind() != reflect.Struct && v.IsNil() {
+			g.printNil(iface, v)
+			return false
+		}
+		g.pr("%s{", v.Type())
+		return true
+
+	case reflect.Interface:
+		if v.IsNil() {
+			g.pr("nil")
+			return false
+		}
+		return true
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			g.printNil(iface, v)
+			return false
+		}
+		if isCompositeKind(v.Elem().Kind()) {
+			g.pr("&")
+		} else {
+			// There is no literal for a pointer to a scalar,
+			// so take the address of a local variable.
+			g.pr("func() %s { v := ", v.Type())
+		}
+		return true
+
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			g.printNil(iface, v)
+		} else {
+			g.out.err = fmt.Errorf("pretty: cannot print a %s as a Go literal", v.Type())
+		}
+	}
+	return false
+}
+
+func (g *goPrinter) leave(n *node) {
+	switch {
+	case n.v.Kind() == reflect.Interface:
+	case n.v.Kind() == reflect.Ptr:
+		if !isCompositeKind(n.v.Elem().Kind()) {
+			g.pr("; return &v }()")
+		}
+	default:
+		g.pr("}")
+	}
+}
+
+func (g *goPrinter) stopped() bool { return g.out.err != nil }
+
+// iface returns whether the value of n is in a context with no static type,
+// such as an element of an []interface{}, so it must carry its own.
+func (g *goPrinter) iface(n *node) bool {
+	p := n.parent
+	if p == nil {
+		return true
+	}
+	t := p.v.Type()
+	switch {
+	case p.v.Kind() == reflect.Interface:
+		return true
+	case p.v.Kind() == reflect.Ptr:
+		return !isCompositeKind(t.Elem().Kind())
+	case p.v.Kind() == reflect.Struct:
+		return n.field.Type.Kind() == reflect.Interface
+	case p.v.Kind() == reflect.Map && n.key:
+		return t.Key().Kind() == reflect.Interface
+	default:
+		return t.Elem().Kind() == reflect.Interface
+	}
+}
+
+// isCompositeKind returns whether values of kind k have composite literals.
+func isCompositeKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.Struct:
+		return true
+	default:
+		return false
+	}
+}
+
+// printScalar prints the literal s for the scalar v,
+// converting it to v's type if the type would otherwise be lost.
+func (g *goPrinter) printScalar(iface bool, v reflect.Value, s string) {
+	if iface && !isDefaultType(v.Type()) {
+		g.pr("%s(%s)", v.Type(), s)
+	} else {
+		g.pr("%s", s)
+	}
+}
+
+// printNil prints nil, converted to v's type if iface is true.
+func (g *goPrinter) printNil(iface bool, v reflect.Value) {
+	if iface {
+		g.pr("(%s)(nil)", v.Type())
+	} else {
+		g.pr("nil")
+	}
+}
+
+// isDefaultType returns whether t is the default type
+// of an untyped constant of its kind.
+func isDefaultType(t reflect.Type) bool {
+	switch t {
+	case reflect.TypeOf(false), reflect.TypeOf(0), reflect.TypeOf(""):
+		return true
+	default:
+		return false
+	}
+}
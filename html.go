@@ -0,0 +1,93 @@
+package pretty
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"reflect"
+)
+
+// HTML returns a value rendered as HTML,
+// with each struct, array, slice, and map as a collapsible
+// <details> element whose <summary> is the value's type.
+//
+// HTML uses Walk, so it follows the same traversal rules as Fprint.
+// Scalars are printed as by Fprint.
+// Each value is wrapped in a div element of class "entry".
+// Text is escaped, and wrapped in span elements of class
+// "type", "name" (for field names and map keys), "value", or "cycle",
+// so that it can be styled.
+//
+// If a PrettyPrint or String method panics, HTML returns an error.
+func HTML(v interface{}) (template.HTML, error) {
+	return defaultRenderer().HTML(v)
+}
+
+// HTML returns a value rendered as HTML, as described by the package-level HTML,
+// using the options of the Renderer's Config.
+func (r *Renderer) HTML(v interface{}) (_ template.HTML, err error) {
+	done := false
+	defer func() {
+		if r := recover(); r != nil || !done {
+			err = panicError(r)
+		}
+	}()
+	hv := htmlVisitor{t: &traversal{c: &r.cfg}}
+	hv.t.walk(&hv, valueOf(v))
+	done = true
+	return template.HTML(hv.buf.String()), nil
+}
+
+type htmlVisitor struct {
+	t   *traversal
+	buf bytes.Buffer
+	// label is the escaped label of the next value;
+	// a field name or map key.
+	label string
+}
+
+func (h *htmlVisitor) EnterStruct(t reflect.Type) { h.enter(t) }
+func (h *htmlVisitor) EnterArray(t reflect.Type)  { h.enter(t) }
+func (h *htmlVisitor) EnterMap(t reflect.Type)    { h.enter(t) }
+
+func (h *htmlVisitor) Field(name string) { h.label = html.EscapeString(name) }
+
+func (h *htmlVisitor) Key(k reflect.Value) { h.label = html.EscapeString(h.t.compactString(k)) }
+
+func (h *htmlVisitor) Scalar(v reflect.Value) {
+	h.begin()
+	h.span("value", h.t.compactString(v))
+	h.buf.WriteString("</div>")
+}
+
+func (h *htmlVisitor) Cycle(reflect.Value) {
+	h.begin()
+	h.span("cycle", h.t.c.CyclePlaceholder)
+	h.buf.WriteString("</div>")
+}
+
+func (h *htmlVisitor) Leave() { h.buf.WriteString("</details></div>") }
+
+func (h *htmlVisitor) enter(t reflect.Type) {
+	h.begin()
+	h.buf.WriteString("<details open><summary>")
+	name := t.Name()
+	if name == "" {
+		name = t.String()
+	}
+	h.span("type", name)
+	h.buf.WriteString("</summary>")
+}
+
+// begin begins the div element for a value and writes its label, if any.
+func (h *htmlVisitor) begin() {
+	h.buf.WriteString(`<div class="entry">`)
+	if h.label != "" {
+		h.buf.WriteString(`<span class="name">` + h.label + "</span>: ")
+		h.label = ""
+	}
+}
+
+func (h *htmlVisitor) span(class, text string) {
+	h.buf.WriteString(`<span class="` + class + `">` + html.EscapeString(text) + "</span>")
+}
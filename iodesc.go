@@ -0,0 +1,56 @@
+package pretty
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ioDetails returns the details shown in the descriptions
+// of common io.Reader and io.Writer implementations, by type.
+var ioDetails = map[reflect.Type]func(reflect.Value) string{
+	reflect.TypeOf((*bytes.Buffer)(nil)): func(v reflect.Value) string {
+		return fmt.Sprintf("len=%d", v.Interface().(*bytes.Buffer).Len())
+	},
+	reflect.TypeOf((*bytes.Reader)(nil)): func(v reflect.Value) string {
+		return fmt.Sprintf("len=%d", v.Interface().(*bytes.Reader).Len())
+	},
+	reflect.TypeOf((*strings.Reader)(nil)): func(v reflect.Value) string {
+		return fmt.Sprintf("len=%d", v.Interface().(*strings.Reader).Len())
+	},
+	reflect.TypeOf((*strings.Builder)(nil)): func(v reflect.Value) string {
+		return fmt.Sprintf("len=%d", v.Interface().(*strings.Builder).Len())
+	},
+	reflect.TypeOf((*bufio.Reader)(nil)): func(v reflect.Value) string {
+		return fmt.Sprintf("size=%d", v.Interface().(*bufio.Reader).Size())
+	},
+	reflect.TypeOf((*bufio.Writer)(nil)): func(v reflect.Value) string {
+		return fmt.Sprintf("size=%d", v.Interface().(*bufio.Writer).Size())
+	},
+}
+
+// isIO returns whether v is an io.Reader or io.Writer.
+func isIO(v reflect.Value) bool {
+	x := v.Interface()
+	_, r := x.(io.Reader)
+	_, w := x.(io.Writer)
+	return r || w
+}
+
+// describeIO returns a short description of the non-nil io.Reader or io.Writer v:
+// its type, followed by the details from ioDetails
+// or the quoted result of a Name method, if any;
+// for example, <*os.File "/tmp/x">.
+func describeIO(v reflect.Value) string {
+	if f, ok := ioDetails[v.Type()]; ok {
+		return fmt.Sprintf("<%s %s>", v.Type(), f(v))
+	}
+	if n, ok := v.Interface().(interface{ Name() string }); ok {
+		return fmt.Sprintf("<%s %s>", v.Type(), strconv.Quote(n.Name()))
+	}
+	return fmt.Sprintf("<%s>", v.Type())
+}
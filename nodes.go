@@ -0,0 +1,89 @@
+//go:build go1.23
+
+package pretty
+
+import (
+	"iter"
+	"reflect"
+	"strings"
+)
+
+// A Node is a value visited by Nodes.
+type Node struct {
+	// Path is the location of Value within the root value,
+	// made of field names, indices, and map keys,
+	// as printed by VerboseCycles; for example, /D/X.
+	// The Path of the root value is /.
+	Path string
+
+	// Value is the value. It is the zero Value for a nil interface
+	// that is not held in a field, element, or map entry.
+	Value reflect.Value
+
+	// Depth is the number of structs, arrays, slices, and maps
+	// enclosing Value.
+	Depth int
+
+	// Cycle is whether Value is already on the path from the root,
+	// in which case the values within it are not visited.
+	Cycle bool
+}
+
+// Nodes returns an iterator over a value and the values within it,
+// in the order that Fprint prints them.
+//
+// Nodes shares its traversal rules with Walk:
+// pointers and interfaces are followed implicitly,
+// so the value they refer to is visited in their place,
+// unexported and empty struct fields are not visited,
+// values that Fprint prints with a PrettyPrint or String method
+// are not traversed, and cycles are pruned.
+func Nodes(v interface{}) iter.Seq[Node] {
+	return defaultRenderer().Nodes(v)
+}
+
+// Nodes returns an iterator over a value and the values within it,
+// as described by the package-level Nodes,
+// using the options of the Renderer's Config.
+func (r *Renderer) Nodes(v interface{}) iter.Seq[Node] {
+	return func(yield func(Node) bool) {
+		t := &traversal{c: &r.cfg}
+		t.traverse(&nodeYielder{t: t, yield: yield}, valueOf(v))
+	}
+}
+
+// A nodeYielder yields the nodes of a traversal as Nodes.
+type nodeYielder struct {
+	t     *traversal
+	yield func(Node) bool
+	done  bool
+}
+
+func (y *nodeYielder) visit(n *node) bool {
+	if n.key {
+		return false
+	}
+	if n.kind == refNode && !n.cycle {
+		// The value referred to is visited in its place.
+		return true
+	}
+	var loc []string
+	for m := n; m != nil; m = m.parent {
+		if m.step != nil {
+			loc = append(loc, y.t.stepString(m.step))
+		}
+	}
+	for i, j := 0, len(loc)-1; i < j; i, j = i+1, j-1 {
+		loc[i], loc[j] = loc[j], loc[i]
+	}
+	node := Node{Path: "/" + strings.Join(loc, "/"), Value: n.v, Depth: len(loc), Cycle: n.cycle}
+	if !y.yield(node) {
+		y.done = true
+		return false
+	}
+	return !n.cycle
+}
+
+func (*nodeYielder) leave(*node) {}
+
+func (y *nodeYielder) stopped() bool { return y.done }
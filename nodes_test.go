@@ -0,0 +1,65 @@
+//go:build go1.23
+
+package pretty
+
+import (
+	"fmt"
+	"strings"
+)
+
+func ExampleNodes() {
+	type Point struct{ X, Y int }
+	type Shape struct {
+		Name   string
+		Points []Point
+		Tags   map[string]bool
+	}
+	s := Shape{
+		Name:   "line",
+		Points: []Point{{X: 1, Y: 2}, {X: 3, Y: 4}},
+		Tags:   map[string]bool{"open": true},
+	}
+	for n := range Nodes(s) {
+		fmt.Printf("%s%s %s\n", strings.Repeat("  ", n.Depth), n.Path, n.Value.Kind())
+	}
+	// Output: / struct
+	//   /Name string
+	//   /Points slice
+	//     /Points/0 struct
+	//       /Points/0/X int
+	//       /Points/0/Y int
+	//     /Points/1 struct
+	//       /Points/1/X int
+	//       /Points/1/Y int
+	//   /Tags map
+	//     /Tags/open bool
+}
+
+func ExampleNodes_break() {
+	type T struct {
+		Name string
+		Next *T
+	}
+	t := &T{Name: "a", Next: &T{Name: "b"}}
+	t.Next.Next = t
+	for n := range Nodes(t) {
+		if n.Cycle {
+			fmt.Println("cycle at", n.Path)
+			break
+		}
+	}
+	// Output: cycle at /Next/Next
+}
+
+func ExampleRenderer_Nodes() {
+	type T struct {
+		A int
+		b string
+	}
+	for n := range With(Config{ShowUnexported: true}).Nodes(&T{A: 1, b: "x"}) {
+		fmt.Println(n.Path, n.Value.Kind())
+	}
+	// Output: / struct
+	// /A int
+	// /b string
+}
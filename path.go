@@ -0,0 +1,187 @@
+package pretty
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrintPath prints a pretty-looking version of a value to os.Stdout,
+// showing only the values at the given paths and those enclosing them.
+//
+// A path is a sequence of struct field names separated by dots,
+// each optionally followed by array or slice indices in brackets;
+// for example, "D.Y", "Items[2].Name", or "[0]".
+// Pointers and interfaces are followed implicitly.
+// The value at the end of a path is printed in full.
+// Struct fields not on any path are replaced by a single TruncationMarker,
+// as are array and slice elements, and the elements that are printed
+// are preceded by their index.
+//
+// If a path does not name a value that Fprint would print,
+// PrintPath returns an error without printing anything,
+// unless IgnoreMissingPaths is set in the Config given to With,
+// in which case such paths are ignored.
+func PrintPath(v interface{}, paths ...string) error {
+	return defaultRenderer().PrintPath(v, paths...)
+}
+
+// PrintPath prints a pretty-looking version of a value to os.Stdout,
+// showing only the values at the given paths, as described by
+// the package-level PrintPath.
+func (r *Renderer) PrintPath(v interface{}, paths ...string) error {
+	return r.FprintPath(os.Stdout, v, paths...)
+}
+
+// FprintPath is like PrintPath, but prints to an io.Writer.
+func (r *Renderer) FprintPath(out io.Writer, v interface{}, paths ...string) error {
+	sel := &selection{}
+	rv := valueOf(v)
+	for _, path := range paths {
+		steps, err := parsePath(path)
+		if err != nil {
+			return err
+		}
+		if !r.cfg.hasPath(rv, steps) {
+			if r.cfg.IgnoreMissingPaths {
+				continue
+			}
+			return fmt.Errorf("pretty: path %q not found", path)
+		}
+		sel.add(steps)
+	}
+	_, err := r.fprint(context.Background(), out, v, sel)
+	return err
+}
+
+// A selection is the set of paths to print within a value.
+// A nil *selection selects the entire value.
+type selection struct {
+	// children are the selections within the selected steps
+	// from the value: field names or indices in brackets.
+	children map[string]*selection
+}
+
+// add adds the path given by steps to s.
+func (s *selection) add(steps []string) {
+	for i, step := range steps {
+		if s.children == nil {
+			s.children = make(map[string]*selection)
+		}
+		child, ok := s.children[step]
+		if ok && child == nil {
+			// An enclosing value is already selected in full.
+			return
+		}
+		if i == len(steps)-1 {
+			s.children[step] = nil
+			return
+		}
+		if child == nil {
+			child = &selection{}
+			s.children[step] = child
+		}
+		s = child
+	}
+}
+
+// child returns the selection within step and whether step is selected.
+// Every step of a nil selection is selected.
+func (s *selection) child(step string) (*selection, bool) {
+	if s == nil {
+		return nil, true
+	}
+	child, ok := s.children[step]
+	return child, ok
+}
+
+// indices returns the selected indices, in increasing order.
+func (s *selection) indices() []int {
+	var is []int
+	for step := range s.children {
+		if i, ok := parseIndex(step); ok {
+			is = append(is, i)
+		}
+	}
+	sort.Ints(is)
+	return is
+}
+
+// parsePath returns the steps of a path:
+// field names, and indices in brackets, such as "[2]".
+func parsePath(path string) ([]string, error) {
+	var steps []string
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+		}
+		if name != "" {
+			steps = append(steps, name)
+		} else if len(steps) > 0 || part == "" {
+			// Only the first part may begin with an index.
+			return nil, fmt.Errorf("pretty: bad path %q", path)
+		}
+		for rest := part[len(name):]; rest != ""; {
+			j := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || j < 0 {
+				return nil, fmt.Errorf("pretty: bad path %q", path)
+			}
+			if _, ok := parseIndex(rest[:j+1]); !ok {
+				return nil, fmt.Errorf("pretty: bad path %q", path)
+			}
+			steps = append(steps, rest[:j+1])
+			rest = rest[j+1:]
+		}
+	}
+	return steps, nil
+}
+
+// parseIndex returns the index of a step of the form "[i]"
+// and whether step has that form.
+func parseIndex(step string) (int, bool) {
+	if len(step) < 3 || step[0] != '[' || step[len(step)-1] != ']' {
+		return 0, false
+	}
+	i, err := strconv.Atoi(step[1 : len(step)-1])
+	return i, err == nil && i >= 0
+}
+
+// hasPath returns whether the path given by steps
+// names a value within v that would be printed.
+func (c *Config) hasPath(v reflect.Value, steps []string) bool {
+	t := &traversal{c: c}
+	for _, step := range steps {
+		k := t.kind(v, false, false)
+		for k == refNode && v.Kind() != reflect.Struct {
+			v = v.Elem()
+			k = t.kind(v, false, false)
+		}
+		if i, ok := parseIndex(step); ok {
+			if k != arrayNode || i >= v.Len() {
+				return false
+			}
+			v = v.Index(i)
+			continue
+		}
+		if k != structNode {
+			return false
+		}
+		var found bool
+		for _, e := range t.elems(v, k) {
+			if e.step == step {
+				v, found = e.v, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
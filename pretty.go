@@ -6,20 +6,188 @@ package pretty
 
 import (
 	"bytes"
+	"context"
+	"encoding"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
+	"unsafe"
 )
 
 // Indent is the string used to denote a single level of indentation.
 // New lines are indented by a series of Indents, based on the level of nesting.
 var Indent = "\t"
 
+// EmptyString, if non-empty, is printed in place of empty string values,
+// making them easier to spot in dense output; for example, `""(empty)`.
+// If EmptyString is empty, empty strings are printed as "".
+var EmptyString = ""
+
+// CyclePlaceholder is printed in place of a value
+// that is already on the path from the root.
+var CyclePlaceholder = "<cycle>"
+
+// InlineUnder, if positive, causes structs, arrays, and slices
+// to be printed on a single line, like Point{X: 1, Y: 2},
+// if that line would be shorter than InlineUnder characters.
+// Longer values are printed on multiple lines as usual.
+var InlineUnder = 0
+
+// MaxOutputBytes, if positive, is the maximum number of bytes
+// that Fprint writes, not counting a final truncation message.
+// If the output would be longer, Fprint stops printing,
+// writes TruncationMarker followed by " (truncated)",
+// and returns ErrTruncated.
+var MaxOutputBytes = 0
+
+// ErrTruncated is returned by Fprint if its output exceeds MaxOutputBytes.
+var ErrTruncated = errors.New("pretty: output truncated")
+
+// SyncMaps, if true, causes sync.Map values to be printed like maps,
+// with their entries in sorted order, rather than as structs.
+var SyncMaps = false
+
+var syncMapType = reflect.TypeOf(sync.Map{})
+
+// TruncationMarker is the string used to mark output that has been elided,
+// such as the remainder of a string truncated by MaxStringLen
+// or a run of zeros collapsed by Sparse.
+var TruncationMarker = "…"
+
+// ShowUnexported, if true, causes unexported struct fields to be printed.
+//
+// Unexported fields are read using package unsafe,
+// which is only possible if the struct is addressable;
+// pass a pointer to the value to print its unexported fields.
+// Unexported fields of non-addressable structs are not printed.
+var ShowUnexported = false
+
+// MaxStringLen, if positive, is the maximum number of bytes
+// of a string value that are printed.
+// Longer strings are truncated at a rune boundary
+// and followed by the number of bytes elided, for example:
+// "abc…" (+4096 bytes).
+var MaxStringLen = 0
+
+// UseStringer, if true, causes values implementing fmt.Stringer
+// to be printed using their String method.
+// Printer takes precedence over fmt.Stringer.
+var UseStringer = false
+
+// UseTextMarshaler, if true, causes values implementing encoding.TextMarshaler
+// to be printed as the quoted result of their MarshalText method.
+// Printer and, if UseStringer is true, fmt.Stringer take precedence.
+// If MarshalText returns an error, the value is printed as if
+// it did not implement encoding.TextMarshaler.
+var UseTextMarshaler = false
+
+// Summary, if true, causes a trailing line to be printed
+// after the value, giving the number of values printed
+// and the size of the output, for example:
+// # 3421 nodes, 18KB.
+var Summary = false
+
+// CanonicalFloats, if true, causes floating point values
+// to be printed in a canonical form:
+// negative zero is printed as zero, and all NaNs are printed identically
+// regardless of their sign and payload bits.
+// This keeps printed output stable, for example in golden files.
+var CanonicalFloats = false
+
+// ShowTopType, if true, causes the value passed to Fprint
+// to be preceded by its type name if it is of a named type.
+// For example, a value of type List []int prints as List[…],
+// and a value of type Celsius float64 as Celsius(37.000000).
+// Structs and maps always print their type names.
+var ShowTopType = false
+
+// ShowNamedScalars, if true, causes values of named bool, numeric,
+// and string types to be printed with their type name,
+// for example, Celsius(37.000000).
+// Values of the predeclared types, such as float64, are printed as usual.
+var ShowNamedScalars = false
+
+// ShowIndirection, if true, causes each pointer to be printed
+// as & followed by the value it points to,
+// so a **T prints as &&T{…}.
+var ShowIndirection = false
+
+// OmitZero, if true, causes struct fields with zero values to be omitted.
+// If any are omitted, TruncationMarker is printed after the remaining fields.
+var OmitZero = false
+
+// ShowInterfaceType, if true, causes each non-nil value held in an interface,
+// such as a struct field of interface type, to be preceded by
+// the interface type and, in parentheses, the dynamic type of the value;
+// for example, io.Reader(*os.File) File{…}.
+var ShowInterfaceType = false
+
+// MapKeysOnly, if true, causes maps to be printed
+// as a single-line list of their sorted keys, without values.
+var MapKeysOnly = false
+
+// Sparse, if true, causes arrays and slices to be printed sparsely:
+// each non-zero element is preceded by its index,
+// and each run of consecutive zero elements is collapsed
+// into a single line giving the number of zeros.
+var Sparse = false
+
+// BoolBitmap, if true, causes arrays and slices of bool
+// to be printed on a single line as 1s and 0s; for example, [1 0 1].
+var BoolBitmap = false
+
+// ShowFieldTypes, if true, causes each struct field name
+// to be followed by the field's declared type; for example, A int: 5.
+var ShowFieldTypes = false
+
+// FlattenEmbedded, if true, causes the fields of an embedded struct
+// to be printed in place of the embedded field, as if they were
+// fields of the embedding struct, much as Go promotes them.
+// A promoted field whose name is also used by another printed field
+// is printed with its qualified name; for example, Inner.X.
+// Embedded pointers, and embedded types printed with
+// a PrettyPrint or String method, are not flattened.
+var FlattenEmbedded = false
+
+// ResetDefaults restores each package-level option, such as Indent,
+// to its default value.
+// It does not affect Renderers returned by With.
+func ResetDefaults() {
+	Indent = "\t"
+	EmptyString = ""
+	CyclePlaceholder = "<cycle>"
+	InlineUnder = 0
+	MaxOutputBytes = 0
+	SyncMaps = false
+	TruncationMarker = "…"
+	ShowUnexported = false
+	MaxStringLen = 0
+	UseStringer = false
+	UseTextMarshaler = false
+	Summary = false
+	CanonicalFloats = false
+	ShowTopType = false
+	ShowNamedScalars = false
+	ShowIndirection = false
+	OmitZero = false
+	ShowInterfaceType = false
+	MapKeysOnly = false
+	Sparse = false
+	BoolBitmap = false
+	ShowFieldTypes = false
+	FlattenEmbedded = false
+}
+
 // A Printer implements the PrettyPrint method.
 type Printer interface {
 	// PrettyPrint returns a string, overriding the default pretty-print format.
@@ -28,29 +196,59 @@ type Printer interface {
 
 // Fprint prints a pretty-looking version of a value to an io.Writer.
 //
-// If a type implementing PrettyPrinter is encountered then its PrettyPrint
-// method is used to print it.
+// If a type with a formatter registered by RegisterFormatter is encountered
+// then the formatter is used to print it.
+// Otherwise, if a type implementing PrettyPrinter is encountered then its PrettyPrint
+// method is used to print it. If UseStringer is true, the same is done
+// for types implementing fmt.Stringer, using their String method,
+// and if UseTextMarshaler is true, for types implementing
+// encoding.TextMarshaler, using their MarshalText method.
+// Methods with pointer receivers are used for values that are not pointers,
+// calling them on a copy of the value if it is not addressable.
 //
 // When printing maps with keys that are strings, integer types, floating point
 // types, or bools, elements are printed in increasing order of their keys (for bools,
-// false < true). When printing maps with any other type of key, elements are
-// printed in an arbitrary order, which may differ with each call to Fprint.
+// false < true). Map keys are printed on a single line. When printing maps with any
+// other type of key, elements are printed in increasing order of the printed keys.
+// The order is the same on each call to Fprint.
 //
-// Fprint prunes cycles. Recall that passing a value makes a copy. The copy is not
-// part of a cycle. If this is undesired, pass a pointer to the value. See the PassPointer
-// and PassValue examples.
-func Fprint(out io.Writer, v interface{}) (err error) {
-	defer func() {
-		if r := recover(); r == nil {
-			return
-		} else if e, ok := r.(error); ok {
-			err = e
-		} else {
-			panic(err)
-		}
-	}()
-	print(out, make(map[reflect.Value]bool), "\n", reflect.ValueOf(v))
-	return err
+// A struct field tagged `pretty:"raw"` is printed without using PrettyPrint
+// or String methods, both for the field's value and the values within it.
+//
+// If v is a reflect.Value, Fprint prints the value that it holds,
+// even if it was obtained from an unexported struct field.
+//
+// Fprint prunes cycles: a pointer, map, or slice that refers to the same
+// memory as one enclosing it is printed as CyclePlaceholder.
+// Recall that passing a value makes a copy. The copy is not part of a cycle.
+// If this is undesired, pass a pointer to the value.
+// See the PassPointer and PassValue examples.
+//
+// If a PrettyPrint or String method panics, Fprint returns an error.
+//
+// Fprint writes to out through a buffer, which it flushes before returning,
+// even if an error occurs. The returned error is the first error
+// encountered writing or flushing.
+//
+// Fprint uses the package-level options.
+// To print with options held in a Config instead, use With.
+func Fprint(out io.Writer, v interface{}) error {
+	return FprintContext(context.Background(), out, v)
+}
+
+// FprintContext is like Fprint, but stops printing
+// if the context is cancelled or its deadline passes,
+// returning the context's error.
+// Output written before then is not retracted.
+func FprintContext(ctx context.Context, out io.Writer, v interface{}) error {
+	return defaultRenderer().FprintContext(ctx, out, v)
+}
+
+// FprintAll prints pretty-looking versions of several values to an io.Writer,
+// each as by Fprint, separated by a blank line.
+// It stops at the first error, returning it.
+func FprintAll(out io.Writer, vs ...interface{}) error {
+	return defaultRenderer().FprintAll(out, vs...)
 }
 
 // Print prints a pretty-looking version of a value to os.Stdout.
@@ -59,195 +257,1600 @@ func Print(v interface{}) error {
 }
 
 // String prints a pretty-looking version of a value, returning it as a string.
+// String panics if Fprint returns an error; StringErr returns it instead.
 func String(v interface{}) string {
+	s, err := StringErr(v)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// StringErr is like String, but returns the error from Fprint, if any,
+// along with the output printed before the error,
+// rather than panicking.
+// For example, if MaxOutputBytes is set, the output may be truncated
+// and the error ErrTruncated.
+func StringErr(v interface{}) (string, error) {
+	return defaultRenderer().StringErr(v)
+}
+
+// Stat prints a pretty-looking version of a value, as by String,
+// returning it along with the number of values printed
+// and the length of the string in bytes.
+// For example, a caller might not log a value with too many nodes.
+func Stat(v interface{}) (s string, nodes, bytes int) {
+	return defaultRenderer().Stat(v)
+}
+
+// EstimateSize returns the number of bytes that Fprint would write
+// for a value, without keeping the output.
+// For example, a logger might truncate a value that is too big.
+// If a PrettyPrint or String method panics,
+// the result is the number of bytes written before the panic.
+func EstimateSize(v interface{}) int {
+	return defaultRenderer().EstimateSize(v)
+}
+
+// checkEvery is the number of values printed
+// between checks of the printer's context.
+const checkEvery = 256
+
+// FprintLabeled prints a label, a colon, and a space,
+// followed by a pretty-looking version of a value, to an io.Writer.
+// Lines after the first are indented with spaces
+// to align beneath the value, not the label.
+func FprintLabeled(out io.Writer, label string, v interface{}) error {
 	buf := bytes.NewBuffer(nil)
-	if err := Fprint(buf, v); err != nil {
+	err := Fprint(buf, v)
+	label += ": "
+	pad := "\n" + strings.Repeat(" ", utf8.RuneCountInString(label))
+	s := label + strings.Replace(buf.String(), "\n", pad, -1)
+	if _, werr := io.WriteString(out, s); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// Labeled prints a label and a pretty-looking version of a value
+// as by FprintLabeled, returning it as a string.
+func Labeled(label string, v interface{}) string {
+	buf := bytes.NewBuffer(nil)
+	if err := FprintLabeled(buf, label, v); err != nil {
 		panic(err)
 	}
 	return buf.String()
 }
 
-// print returns whether the value is of a non-composite type.
-func print(out io.Writer, path map[reflect.Value]bool, indent string, v reflect.Value) {
-	if !v.IsValid() {
-		pr(out, "nil")
+type printer struct {
+	cfg *Config
+	// ctx, if non-nil, is checked every checkEvery nodes.
+	ctx context.Context
+	out *errWriter
+	// t is the traversal of the value being printed.
+	t *traversal
+	// path holds the values on the path from the root,
+	// mapped to their location if VerboseCycles is true.
+	path map[identity]string
+	// loc is the location of the value being printed,
+	// as a list of steps, if VerboseCycles is true.
+	loc []string
+
+	// nodes is the number of values printed.
+	nodes int
+
+	// compact is whether to print composite values on a single line.
+	compact bool
+
+	// dedup, if non-nil, tracks shared pointers for DedupPointers.
+	dedup *dedup
+
+	// sel is the selection of the value being printed, for PrintPath.
+	sel *selection
+
+	// inGetter is whether the value being printed is the result of a getter.
+	inGetter bool
+
+	// raw is whether to ignore PrettyPrint and String methods.
+	// It is set while printing a field tagged `pretty:"raw"`.
+	raw bool
+
+	// runes is whether to print int32 values as quoted characters.
+	// It is set while printing a field tagged `pretty:"rune"`,
+	// if RunesAsChars is true.
+	runes bool
+}
+
+// valueOf returns v if it is a reflect.Value,
+// and otherwise returns reflect.ValueOf(v).
+func valueOf(v interface{}) reflect.Value {
+	if rv, ok := v.(reflect.Value); ok {
+		return rv
+	}
+	return reflect.ValueOf(v)
+}
+
+// printTop prints the root value.
+func (p *printer) printTop(v reflect.Value) {
+	if !p.cfg.ShowTopType || !v.IsValid() || v.Type().Name() == "" {
+		p.print(p.cfg.Newline, v)
 		return
 	}
-	if path[v] {
-		pr(out, "<cycle>")
+	if _, ok := p.cfg.custom(v); ok {
+		p.print(p.cfg.Newline, v)
 		return
 	}
-	path[v] = true
-	defer func() { path[v] = false }()
-	if pper, ok := v.Interface().(Printer); ok {
-		if v.Kind() == reflect.Ptr && v.IsNil() {
-			pr(out, "nil")
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+		p.print(p.cfg.Newline, v)
+	case reflect.Array, reflect.Slice:
+		if v.Kind() == reflect.Slice && v.IsNil() || p.cfg.ShowLenCap {
+			// ShowLenCap shows the name.
+			p.print(p.cfg.Newline, v)
+			return
+		}
+		p.pr("%s", v.Type().Name())
+		p.print(p.cfg.Newline, v)
+	default:
+		if p.cfg.ShowNamedScalars && isNamedScalar(v.Type()) {
+			// print already shows the name.
+			p.print(p.cfg.Newline, v)
+			return
+		}
+		p.pr("%s(", v.Type().Name())
+		p.print(p.cfg.Newline, v)
+		p.pr(")")
+	}
+}
+
+func (p *printer) print(indent string, v reflect.Value) {
+	p.nodes++
+	if p.ctx != nil && p.nodes%checkEvery == 0 && p.out.err == nil {
+		p.out.err = p.ctx.Err()
+	}
+	if p.out.err != nil {
+		return
+	}
+	if !v.IsValid() {
+		p.pr("nil")
+		return
+	}
+	if p.dedup != nil {
+		switch n, seen := p.dedup.id(v); {
+		case seen:
+			p.pr("↺ #%d", n)
+			return
+		case n > 0:
+			p.pr("#%d ", n)
+		}
+	}
+	if id, ok := identify(v); ok {
+		if loc, ok := p.path[id]; ok {
+			p.printCycle(loc)
+			return
+		}
+		if p.cfg.VerboseCycles {
+			p.path[id] = "/" + strings.Join(p.loc, "/")
 		} else {
-			pr(out, "%s", pper.PrettyPrint())
+			p.path[id] = ""
 		}
+		defer delete(p.path, id)
+	}
+	k := p.t.kind(v, p.raw, p.inGetter)
+	if k == customNode {
+		f, _ := p.cfg.custom(v)
+		if s, ok := f(); ok {
+			p.printCustom(indent, s)
+			return
+		}
+		k = p.t.kind(v, true, p.inGetter)
+	}
+	switch {
+	case k == nullNode:
+		p.pr("null")
+		return
+	case k == refNode && v.Kind() == reflect.Struct:
+		// A null wrapper prints as its value.
+		e := p.t.elems(v, k)[0]
+		p.push(e.step)
+		p.print(indent, e.v)
+		p.pop()
+		return
+	case k == gettersNode:
+		p.printGetters(indent, v, p.t.callGetters(v))
+		return
+	case k == mapNode:
+		p.printMap(indent, v)
+		return
+	}
+	if p.cfg.ShowNamedScalars && isNamedScalar(v.Type()) {
+		p.pr("%s(", v.Type().Name())
+		p.printValue(indent, v)
+		p.pr(")")
+		return
+	}
+	p.printValue(indent, v)
+}
+
+// printCycle prints CyclePlaceholder for a cycle back to the value at loc.
+// If VerboseCycles is true, loc is inserted before a final >
+// of the placeholder, or otherwise appended to it.
+func (p *printer) printCycle(loc string) {
+	ph := p.cfg.CyclePlaceholder
+	switch {
+	case !p.cfg.VerboseCycles:
+		p.pr("%s", ph)
+	case strings.HasSuffix(ph, ">"):
+		p.pr("%s -> %s>", ph[:len(ph)-1], loc)
+	default:
+		p.pr("%s -> %s", ph, loc)
+	}
+}
+
+// push adds a step to the location of the value being printed,
+// if VerboseCycles is true.
+// The step is a field name, an index, or a map key.
+// A string map key is used as it is,
+// and other map keys are printed on a single line.
+func (p *printer) push(step interface{}) {
+	if !p.cfg.VerboseCycles {
 		return
 	}
+	p.loc = append(p.loc, p.t.stepString(step))
+}
+
+// pop removes the last step added by push.
+func (p *printer) pop() {
+	if p.cfg.VerboseCycles {
+		p.loc = p.loc[:len(p.loc)-1]
+	}
+}
+
+// printCustom prints s, the result of a PrettyPrint, String, or similar method.
+func (p *printer) printCustom(indent, s string) {
+	if !p.compact {
+		// Align continuation lines with the value's first line.
+		pad := p.out.col - advance(0, strings.TrimLeft(indent, "\r\n"))
+		if pad < 0 {
+			pad = 0
+		}
+		s = strings.Replace(s, "\n", indent+strings.Repeat(" ", pad), -1)
+	}
+	p.pr("%s", s)
+}
+
+// printValue prints v according to its kind.
+func (p *printer) printValue(indent string, v reflect.Value) {
 	switch v.Kind() {
 	case reflect.Bool:
-		pr(out, "%t", v.Bool())
+		p.pr("%t", v.Bool())
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		pr(out, "%d", v.Int())
+		if name, ok := integerName(v); ok {
+			p.pr("%s", name)
+		} else if p.runes && v.Kind() == reflect.Int32 {
+			p.pr("%s", strconv.QuoteRune(rune(v.Int())))
+		} else {
+			p.pr("%d", v.Int())
+		}
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		pr(out, "%d", v.Uint())
+		if name, ok := integerName(v); ok {
+			p.pr("%s", name)
+		} else if p.cfg.ShowPointers && v.Kind() == reflect.Uintptr {
+			p.pr("%#x", v.Uint())
+		} else {
+			p.pr("%d", v.Uint())
+		}
 
 	case reflect.Float32, reflect.Float64:
-		pr(out, "%f", v.Float())
+		p.pr("%s", p.cfg.formatFloat(v.Float()))
 
 	case reflect.Complex64, reflect.Complex128:
-		pr(out, "%f", v.Complex())
+		p.pr("%s", p.cfg.formatComplex(v.Complex()))
 
 	case reflect.Array, reflect.Slice:
-		printArray(out, path, indent, v)
+		p.printArray(indent, v)
 
 	case reflect.Interface, reflect.Ptr:
 		if v.IsNil() {
-			pr(out, "nil")
-		} else {
-			print(out, path, indent, v.Elem())
+			p.pr("nil")
+			return
 		}
+		if p.cfg.ShowIndirection && v.Kind() == reflect.Ptr {
+			p.pr("&")
+		}
+		if p.cfg.ShowInterfaceType && v.Kind() == reflect.Interface {
+			p.pr("%s(%s) ", v.Type(), v.Elem().Type())
+		}
+		if e := v.Elem(); v.Kind() == reflect.Interface && e.Kind() == reflect.Ptr && e.IsNil() {
+			// Unlike a nil interface, this interface is not == nil.
+			p.pr("(%s)(nil)", e.Type())
+			return
+		}
+		p.print(indent, v.Elem())
 
 	case reflect.String:
-		pr(out, strconv.Quote(v.String()))
+		p.printStringValue(indent, v.String())
 
 	case reflect.Struct:
-		printStruct(out, path, indent, v)
+		p.printStruct(indent, v)
 
 	case reflect.Map:
-		printMap(out, path, indent, v)
+		p.printMap(indent, v)
 
 	case reflect.Chan:
-		pr(out, "<chan>")
+		if v.IsNil() {
+			p.pr("nil")
+		} else {
+			t := v.Type()
+			p.pr("%s %s (len %d, cap %d)", t.ChanDir(), t.Elem(), v.Len(), v.Cap())
+		}
 	case reflect.Func:
-		pr(out, "<function>")
+		if v.IsNil() {
+			p.pr("nil")
+		} else {
+			p.pr("%s", v.Type())
+		}
 	case reflect.UnsafePointer:
-		pr(out, "<unsafe pointer>")
+		switch {
+		case !p.cfg.ShowPointers:
+			p.pr("<unsafe pointer>")
+		case v.IsNil():
+			p.pr("nil")
+		default:
+			p.pr("%#x", v.Pointer())
+		}
 	case reflect.Invalid:
-		pr(out, "<invalid>")
+		p.pr("<invalid>")
+	}
+}
+
+// custom returns a function returning the string for v
+// given by its registered formatter, its PrettyPrint method,
+// or, if enabled by c, its String or MarshalText method.
+// The boolean is false if v has no such formatter or method.
+func (c *Config) custom(v reflect.Value) (func() (string, bool), bool) {
+	if f, ok := formatter(v.Type()); ok {
+		return func() (string, bool) { return f(v), true }, true
+	}
+	if !v.CanInterface() {
+		// v was obtained from an unexported field,
+		// so its methods cannot be called.
+		return nil, false
+	}
+	if v.Kind() == reflect.Interface {
+		// Methods are checked on the dynamic value.
+		return nil, false
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		if _, ok := c.customMethod(v); ok {
+			return func() (string, bool) { return "nil", true }, true
+		}
+		return nil, false
+	}
+	return c.customMethod(v)
+}
+
+// customMethod returns the function used to print v
+// using its PrettyPrint, String, or MarshalText method,
+// or a description if v is an io.Reader or io.Writer,
+// and whether there is one.
+// The function returns false if MarshalText returns an error,
+// in which case v is printed as if it did not implement
+// encoding.TextMarshaler.
+func (c *Config) customMethod(v reflect.Value) (func() (string, bool), bool) {
+	if x, ok := methods(v, printerType); ok {
+		return func() (string, bool) { return x.(Printer).PrettyPrint(), true }, true
+	}
+	if x, ok := methods(v, stringerType); ok && c.UseStringer && !(c.RawStringer && isNumber(v.Kind())) {
+		return func() (string, bool) { return x.(fmt.Stringer).String(), true }, true
+	}
+	if x, ok := methods(v, textMarshalerType); ok && c.UseTextMarshaler {
+		return func() (string, bool) {
+			text, err := x.(encoding.TextMarshaler).MarshalText()
+			return strconv.Quote(string(text)), err == nil
+		}, true
+	}
+	if c.DescribeIO && isIO(v) {
+		return func() (string, bool) { return describeIO(v), true }, true
+	}
+	return nil, false
+}
+
+var (
+	printerType       = reflect.TypeOf((*Printer)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// methods returns v as an interface{} that implements the interface iface,
+// and whether there is one.
+// If v is not a pointer and only a pointer to v implements iface,
+// the result is the address of v, or of a copy of v if it is not addressable;
+// so methods with pointer receivers are used as if v were addressable.
+func methods(v reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if v.Type().Implements(iface) {
+		return v.Interface(), true
+	}
+	if v.Kind() == reflect.Ptr || !reflect.PtrTo(v.Type()).Implements(iface) {
+		return nil, false
+	}
+	if v.CanAddr() {
+		return v.Addr().Interface(), true
 	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface(), true
 }
 
-func printArray(out io.Writer, path map[reflect.Value]bool, indent string, v reflect.Value) {
+func (p *printer) printArray(indent string, v reflect.Value) {
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		p.pr("nil")
+		return
+	}
+	if p.cfg.RuneSlicesAsString && v.Type().Elem() == runeType {
+		var b strings.Builder
+		for i := 0; i < v.Len(); i++ {
+			b.WriteRune(rune(v.Index(i).Int()))
+		}
+		p.nodes += v.Len()
+		p.printStringValue(indent, b.String())
+		return
+	}
+	if p.cfg.ShowLenCap {
+		if v.Kind() == reflect.Slice {
+			p.pr("%s(len %d, cap %d)", structName(v.Type()), v.Len(), v.Cap())
+		} else {
+			p.pr("%s(len %d)", structName(v.Type()), v.Len())
+		}
+	}
+	p.printElems(indent, v)
+}
+
+// runeType is the type rune, which is the same as int32.
+var runeType = reflect.TypeOf(rune(0))
+
+// printElems prints the elements of the non-nil array or slice v.
+func (p *printer) printElems(indent string, v reflect.Value) {
+	open, close := p.cfg.delims(reflect.Slice)
 	if v.Len() == 0 {
-		pr(out, "[]")
+		p.pr("%s%s", open, close)
+		return
+	}
+	if p.sel != nil {
+		p.printSelected(indent, v)
+		return
+	}
+	if p.cfg.BoolBitmap && v.Type().Elem().Kind() == reflect.Bool {
+		if _, ok := p.cfg.custom(reflect.Zero(v.Type().Elem())); !ok {
+			p.printBitmap(v)
+			return
+		}
+	}
+	if p.cfg.MatrixLayout && !p.compact {
+		if cells, ok := p.matrixCells(v); ok {
+			p.printMatrix(indent, v, cells)
+			return
+		}
+	}
+	if p.printInline(func(q *printer) { q.printElems(indent, v) }) {
 		return
 	}
-	pr(out, "[")
-	indent2 := indent + Indent
+	p.pr("%s", open)
+	indent2 := indent + p.cfg.Indent
+	if p.cfg.Sparse {
+		p.printSparse(indent2, v)
+	} else {
+		for i := 0; i < v.Len(); i++ {
+			p.line(indent2, i)
+			p.push(i)
+			p.print(indent2, v.Index(i))
+			p.pop()
+		}
+	}
+	p.end(indent, close)
+}
+
+// printSelected prints the elements of the array or slice v
+// that are selected by p.sel, labeled by their index,
+// with TruncationMarker in place of the others.
+func (p *printer) printSelected(indent string, v reflect.Value) {
+	open, close := p.cfg.delims(reflect.Slice)
+	p.pr("%s", open)
+	indent2 := indent + p.cfg.Indent
+	sel := p.sel
+	var line int
+	for _, i := range sel.indices() {
+		if i >= v.Len() {
+			continue
+		}
+		p.line(indent2, line)
+		line++
+		p.pr("%d: ", i)
+		p.sel, _ = sel.child("[" + strconv.Itoa(i) + "]")
+		p.push(i)
+		p.print(indent2, v.Index(i))
+		p.pop()
+	}
+	p.sel = sel
+	if line < v.Len() {
+		p.line(indent2, line)
+		p.pr("%s", p.cfg.TruncationMarker)
+	}
+	p.end(indent, close)
+}
+
+// printBitmap prints the array or slice of bool v as 1s and 0s.
+func (p *printer) printBitmap(v reflect.Value) {
+	var b strings.Builder
 	for i := 0; i < v.Len(); i++ {
-		pr(out, indent2)
-		print(out, path, indent2, v.Index(i))
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte('0' + byte(boolInt(v.Index(i).Bool())))
 	}
-	pr(out, indent+"]")
+	p.nodes += v.Len()
+	open, close := p.cfg.delims(reflect.Slice)
+	p.pr("%s%s%s", open, b.String(), close)
 }
 
-func printStruct(out io.Writer, path map[reflect.Value]bool, indent string, v reflect.Value) {
-	t := v.Type()
-	pr(out, "%s{", t.Name())
-	indent2 := indent + Indent
+// embeddedInterface returns whether f is an embedded interface.
+func embeddedInterface(f *reflect.StructField) bool {
+	return f.Anonymous && f.Type.Kind() == reflect.Interface
+}
 
-	var n int
-	var complex bool
-	for i := 0; i < t.NumField(); i++ {
+// printEmbeddedInterface prints the value of an embedded interface field
+// preceded by the interface type and the type of its value,
+// or if it is nil, as <nil T> for the interface type T.
+func (p *printer) printEmbeddedInterface(indent string, v reflect.Value) {
+	if v.IsNil() {
+		p.pr("<nil %s>", v.Type())
+		return
+	}
+	if !p.cfg.ShowInterfaceType {
+		// Otherwise, print shows the types.
+		p.pr("%s(%s) ", v.Type(), v.Elem().Type())
+	}
+	p.print(indent, v)
+}
+
+// printVariant prints the value of the non-nil union interface v
+// on a single line, with its type, omitting any pointers.
+func (p *printer) printVariant(v reflect.Value) {
+	e := v.Elem()
+	for e.Kind() == reflect.Ptr && !e.IsNil() {
+		if _, ok := p.cfg.custom(e); ok {
+			break
+		}
+		e = e.Elem()
+	}
+	if e.Kind() == reflect.Struct {
+		if _, ok := p.cfg.custom(e); !ok {
+			p.printCompact(e)
+			return
+		}
+	}
+	p.pr("%s(", structName(e.Type()))
+	p.printCompact(e)
+	p.pr(")")
+}
+
+// nullWrapper returns the index of the value field of the struct type t
+// and whether t is a null wrapper like sql.NullString:
+// a struct with two exported fields, one of which is Valid, a bool.
+func nullWrapper(t reflect.Type) (int, bool) {
+	if t.NumField() != 2 {
+		return 0, false
+	}
+	valid := -1
+	for i := 0; i < 2; i++ {
 		f := t.Field(i)
-		if !exported(&f) || isEmpty(v.Field(i)) {
+		if !exported(&f) {
+			return 0, false
+		}
+		if f.Name == "Valid" && f.Type.Kind() == reflect.Bool {
+			valid = i
+		}
+	}
+	if valid < 0 {
+		return 0, false
+	}
+	return 1 - valid, true
+}
+
+// matrixCells returns the elements of the elements of v, printed on a single line,
+// and whether v is a rectangular array or slice of arrays or slices
+// whose elements are not pointers, structs, arrays, slices, or maps.
+func (p *printer) matrixCells(v reflect.Value) ([][]string, bool) {
+	cells := make([][]string, v.Len())
+	for i := range cells {
+		row := v.Index(i)
+		if k := row.Kind(); k != reflect.Array && k != reflect.Slice {
+			return nil, false
+		}
+		if row.Len() == 0 || row.Len() != v.Index(0).Len() {
+			return nil, false
+		}
+		if _, ok := p.cfg.custom(row); ok {
+			return nil, false
+		}
+		for j := 0; j < row.Len(); j++ {
+			cell := row.Index(j)
+			if isComplex(cell) || cell.Kind() == reflect.Ptr {
+				return nil, false
+			}
+			s := p.t.compactString(cell)
+			if strings.Contains(s, "\n") {
+				return nil, false
+			}
+			cells[i] = append(cells[i], s)
+		}
+	}
+	return cells, true
+}
+
+// printMatrix prints the array or slice v as a grid of its cells,
+// one row per line, with each column padded to the same width.
+// Numbers are right-justified, and other values left-justified.
+func (p *printer) printMatrix(indent string, v reflect.Value, cells [][]string) {
+	widths := make([]int, len(cells[0]))
+	for _, row := range cells {
+		for j, s := range row {
+			if n := utf8.RuneCountInString(s); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+	p.nodes += len(cells) * (len(widths) + 1)
+	open, close := p.cfg.delims(reflect.Slice)
+	p.pr("%s", open)
+	indent2 := indent + p.cfg.Indent
+	for i, row := range cells {
+		p.line(indent2, i)
+		p.pr("%s", open)
+		for j, s := range row {
+			if j > 0 {
+				p.pr(" ")
+			}
+			pad := strings.Repeat(" ", widths[j]-utf8.RuneCountInString(s))
+			cell := v.Index(i).Index(j)
+			if cell.Kind() == reflect.Interface {
+				cell = cell.Elem()
+			}
+			if isNumber(cell.Kind()) {
+				p.pr("%s%s", pad, s)
+			} else {
+				p.pr("%s%s", s, pad)
+			}
+		}
+		p.pr("%s", close)
+	}
+	p.end(indent, close)
+}
+
+// printSparse prints the elements of the array or slice v,
+// labeling each non-zero element with its index,
+// and collapsing each run of zero elements.
+func (p *printer) printSparse(indent string, v reflect.Value) {
+	var line int
+	for i := 0; i < v.Len(); {
+		p.line(indent, line)
+		line++
+		if !v.Index(i).IsZero() {
+			p.pr("%d: ", i)
+			p.push(i)
+			p.print(indent, v.Index(i))
+			p.pop()
+			i++
 			continue
 		}
+		n := 0
+		for ; i < v.Len() && v.Index(i).IsZero(); i++ {
+			n++
+		}
+		if n == 1 {
+			p.pr("%s 1 zero %[1]s", p.cfg.TruncationMarker)
+		} else {
+			p.pr("%s %d zeros %[1]s", p.cfg.TruncationMarker, n)
+		}
+	}
+}
+
+func (p *printer) printStruct(indent string, v reflect.Value) {
+	if p.printInline(func(q *printer) { q.printStruct(indent, v) }) {
+		return
+	}
+	open, close := p.cfg.delims(reflect.Struct)
+	p.pr("%s%s", structName(v.Type()), open)
+	indent2 := indent + p.cfg.Indent
+
+	fields, omitted := p.cfg.structFields(v)
+	sel := p.sel
+	if sel != nil {
+		var selected []structField
+		for _, f := range fields {
+			if _, ok := sel.child(f.Name); ok {
+				selected = append(selected, f)
+			}
+		}
+		omitted = omitted || len(selected) < len(fields)
+		fields = selected
+	}
+	var more int
+	if p.cfg.MaxFields > 0 && len(fields) > p.cfg.MaxFields {
+		more = len(fields) - p.cfg.MaxFields
+		fields = fields[:p.cfg.MaxFields]
+		omitted = true
+	}
+	n := len(fields)
+	if omitted {
 		n++
-		if isComplex(v.Field(i)) {
+	}
+	var complex bool
+	for _, f := range fields {
+		if isComplex(f.v) {
 			complex = true
 		}
 	}
-	for i := 0; i < t.NumField(); i++ {
-		f := t.Field(i)
-		if !exported(&f) || isEmpty(v.Field(i)) {
-			continue
+	labels := make([]string, len(fields))
+	var width int
+	unions := make([]bool, len(fields))
+	for i, f := range fields {
+		sep := ": "
+		if unions[i] = isUnion(f.Type) && !f.v.IsNil(); unions[i] {
+			sep = " = "
 		}
-		if n > 1 || complex {
-			pr(out, "%s%s: ", indent2, f.Name)
+		if p.cfg.ShowFieldTypes {
+			labels[i] = fmt.Sprintf("%s %s%s", f.Name, f.Type, sep)
 		} else {
-			pr(out, "%s: ", f.Name)
+			labels[i] = f.Name + sep
+		}
+		if w := utf8.RuneCountInString(labels[i]); w > width {
+			width = w
+		}
+	}
+	align := p.cfg.AlignFields && !p.compact && !complex && n > 1
+	for i, f := range fields {
+		if n > 1 || complex {
+			p.line(indent2, i)
+		}
+		p.pr("%s", labels[i])
+		if align {
+			p.pr("%s", strings.Repeat(" ", width-utf8.RuneCountInString(labels[i])))
+		}
+		raw, runes := p.raw, p.runes
+		p.raw = raw || hasTag(f.StructField, "raw")
+		p.runes = runes || p.cfg.RunesAsChars && hasTag(f.StructField, "rune")
+		p.sel, _ = sel.child(f.Name)
+		p.push(f.Name)
+		switch {
+		case unions[i]:
+			p.printVariant(f.v)
+		case p.cfg.ShowEmbeddedInterfaces && embeddedInterface(&f.StructField):
+			p.printEmbeddedInterface(indent2, f.v)
+		default:
+			p.print(indent2, f.v)
+		}
+		p.pop()
+		p.raw, p.runes = raw, runes
+	}
+	p.sel = sel
+	if omitted {
+		if n > 1 || complex {
+			p.line(indent2, len(fields))
+		}
+		p.pr("%s", p.cfg.TruncationMarker)
+		if more > 0 {
+			p.pr(" (+%d more)", more)
 		}
-		print(out, path, indent2, v.Field(i))
 	}
 	if n == 0 || n == 1 && !complex {
 		// Don't put } on its own line.
 		indent = ""
 	}
-	pr(out, "%s}", indent)
+	p.end(indent, close)
+}
+
+// structName returns the name printed before the fields of a struct of type t.
+// It is the name of the type, including its type arguments if it is generic,
+// such as Box[int], or if the type is unnamed, the type itself.
+// Import paths in type arguments are shortened to the package name,
+// so Box[github.com/x/y.T] is printed as Box[y.T].
+func structName(t reflect.Type) string {
+	if t.Name() == "" {
+		return t.String()
+	}
+	return importPath.ReplaceAllString(t.Name(), "")
+}
+
+// importPath matches the directories of an import path, up to the package name.
+var importPath = regexp.MustCompile(`([\w.~-]+/)+`)
+
+// A structField is a struct field to print, and its value.
+type structField struct {
+	reflect.StructField
+	v reflect.Value
+	// qualified is the field's name qualified by the names
+	// of the embedded fields it was promoted through, if any.
+	qualified string
 }
 
-func printMap(out io.Writer, path map[reflect.Value]bool, indent string, v reflect.Value) {
+// structFields returns the fields of the struct v that should be printed,
+// and whether any non-empty fields were omitted because of OmitZero
+// or SkipKinds.
+func (c *Config) structFields(v reflect.Value) (fields []structField, omitted bool) {
+	fields, omitted = c.appendStructFields(nil, "", v)
+	if !c.FlattenEmbedded {
+		return fields, omitted
+	}
+	names := make(map[string]int)
+	for _, f := range fields {
+		names[f.Name]++
+	}
+	for i, f := range fields {
+		if names[f.Name] > 1 {
+			fields[i].Name = f.qualified
+		}
+	}
+	return fields, omitted
+}
+
+// appendStructFields appends to fields the fields of the struct v
+// that should be printed, qualifying their names with prefix.
+func (c *Config) appendStructFields(fields []structField, prefix string, v reflect.Value) ([]structField, bool) {
+	var omitted bool
 	t := v.Type()
-	pr(out, "%s{", t.Name())
-	indent2 := indent + Indent
-	keys := v.MapKeys()
-	sort.Sort(values(keys)) // Just a best-effort sorting.
-	for _, k := range keys {
-		pr(out, "%s", indent2)
-		print(out, path, indent2, k)
-		pr(out, ": ")
-		print(out, path, indent2, v.MapIndex(k))
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if c.flatten(&sf, v.Field(i)) {
+			var o bool
+			fields, o = c.appendStructFields(fields, prefix+sf.Name+".", v.Field(i))
+			omitted = omitted || o
+			continue
+		}
+		f, ok := c.field(v, i)
+		switch {
+		case !ok || isEmpty(f) && !(c.ShowEmbeddedInterfaces && embeddedInterface(&sf)):
+			continue
+		case c.OmitZero && f.IsZero(), c.skip(f):
+			omitted = true
+			continue
+		}
+		fields = append(fields, structField{StructField: sf, v: f, qualified: prefix + sf.Name})
 	}
-	pr(out, "%s}", indent)
+	return fields, omitted
 }
 
-type values []reflect.Value
+// flatten returns whether the struct field f, with value v,
+// should be replaced by its fields because of FlattenEmbedded.
+func (c *Config) flatten(f *reflect.StructField, v reflect.Value) bool {
+	if !c.FlattenEmbedded || !f.Anonymous || v.Kind() != reflect.Struct || hasTag(*f, "raw") {
+		return false
+	}
+	if c.FieldVisible != nil && !c.FieldVisible(*f) {
+		return false
+	}
+	_, ok := c.custom(v)
+	return !ok
+}
 
-func (vs values) Len() int      { return len(vs) }
-func (vs values) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
-func (vs values) Less(i, j int) bool {
-	a, b := vs[i], vs[j]
-	if a.Kind() != b.Kind() {
-		panic("different kinds")
+// printMap prints a map, or a sync.Map if SyncMaps is true,
+// with the type name sync.Map.
+func (p *printer) printMap(indent string, v reflect.Value) {
+	name := v.Type().Name()
+	es := p.t.mapEntries(v)
+	if v.Kind() != reflect.Map {
+		name = "sync.Map"
+		if len(es) == 0 {
+			open, close := p.cfg.delims(reflect.Map)
+			p.pr("%s%s%s", name, open, close)
+			return
+		}
 	}
+	p.printMapEntries(name, indent, es)
+}
 
-	switch a.Kind() {
-	case reflect.Bool:
-		var aint, bint int
-		if a.Bool() {
-			aint = 1
+// printMapEntries prints the sorted entries of a map with the given type name.
+func (p *printer) printMapEntries(name, indent string, es []mapEntry) {
+	if p.cfg.Width > 0 && p.printInline(func(q *printer) { q.printMapEntries(name, indent, es) }) {
+		return
+	}
+	open, close := p.cfg.delims(reflect.Map)
+	p.pr("%s%s", name, open)
+	if p.cfg.MapKeysOnly {
+		for i, e := range es {
+			if i > 0 {
+				p.pr(", ")
+			}
+			p.printCompact(e.k)
+		}
+		p.pr("%s", close)
+		return
+	}
+	if p.cfg.InlineScalarMaps && !p.compact && !hasComplexValues(es) {
+		p.compact = true
+		defer func() { p.compact = false }()
+	}
+	indent2 := indent + p.cfg.Indent
+	for i, e := range es {
+		p.line(indent2, i)
+		p.printCompact(e.k)
+		p.pr(": ")
+		p.push(e.k)
+		p.print(indent2, e.v)
+		p.pop()
+	}
+	p.end(indent, close)
+}
+
+// printInline prints a composite value on a single line,
+// if its single-line form is shorter than InlineUnder characters
+// and fits within Width columns,
+// and returns whether it did so.
+// The value is printed by calling f with a compact-mode copy of p.
+func (p *printer) printInline(f func(q *printer)) bool {
+	n := p.inlineLimit()
+	if p.compact || n < 0 {
+		return false
+	}
+	buf := bytes.NewBuffer(nil)
+	q := *p
+	q.out = &errWriter{w: &runeLimitWriter{w: buf, n: n}}
+	q.nodes = 0
+	q.compact = true
+	if p.dedup != nil {
+		// Numbers assigned while trying are kept only on success.
+		q.dedup = p.dedup.clone()
+	}
+	f(&q)
+	switch q.out.err {
+	case nil:
+	case errTooLong:
+		// The value is printed again, and its nodes counted then.
+		return false
+	default:
+		// The context was cancelled.
+		p.out.err = q.out.err
+		return true
+	}
+	p.nodes += q.nodes
+	p.dedup = q.dedup
+	p.pr("%s", buf.String())
+	return true
+}
+
+// inlineLimit returns the maximum number of characters
+// in the single-line form of a value printed by printInline,
+// or -1 if neither InlineUnder nor Width is set,
+// or the current line is already Width columns long.
+func (p *printer) inlineLimit() int {
+	n := -1
+	if p.cfg.InlineUnder > 0 {
+		n = p.cfg.InlineUnder - 1
+	}
+	if p.cfg.Width > 0 {
+		w := p.cfg.Width - utf8.RuneCountInString(p.cfg.LinePrefix) - p.out.col
+		if n < 0 || w < n {
+			n = w
+		}
+	}
+	return n
+}
+
+// truncWriter writes at most n bytes to w,
+// followed by marker and " (truncated)".
+// Writes beyond n bytes return ErrTruncated.
+type truncWriter struct {
+	w      io.Writer
+	n      int
+	marker string
+}
+
+func (t *truncWriter) Write(p []byte) (int, error) {
+	if len(p) <= t.n {
+		t.n -= len(p)
+		return t.w.Write(p)
+	}
+	// Don't split a rune.
+	n := t.n
+	for n > 0 && !utf8.RuneStart(p[n]) {
+		n--
+	}
+	t.n = 0
+	if _, err := t.w.Write(p[:n]); err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(t.w, t.marker+" (truncated)"); err != nil {
+		return 0, err
+	}
+	return n, ErrTruncated
+}
+
+// A capWriter writes at most n bytes to w,
+// returning ErrTruncated for a write that would exceed them.
+type capWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *capWriter) Write(p []byte) (int, error) {
+	if len(p) <= c.n {
+		c.n -= len(p)
+		return c.w.Write(p)
+	}
+	n, err := c.w.Write(p[:c.n])
+	c.n -= n
+	if err == nil {
+		err = ErrTruncated
+	}
+	return n, err
+}
+
+// errWriter writes to w, recording the first error.
+// Once an error occurs, later writes do nothing and return it.
+type errWriter struct {
+	w   io.Writer
+	err error
+	// n is the number of bytes written to w.
+	n int
+	// col is the column following the last byte written to w,
+	// with tabs advancing to the next multiple of 8.
+	col int
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n, err := e.w.Write(p)
+	e.n += n
+	e.err = err
+	line := p[:n]
+	if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+		e.col = 0
+		line = line[i+1:]
+	}
+	e.col = advance(e.col, string(line))
+	return n, err
+}
+
+// advance returns the column following s written at column col,
+// with tabs advancing to the next multiple of 8.
+func advance(col int, s string) int {
+	for _, r := range s {
+		if r == '\t' {
+			col += 8 - col%8
+		} else {
+			col++
 		}
-		if b.Bool() {
-			bint = 1
+	}
+	return col
+}
+
+// prefixWriter writes to w, writing prefix at the beginning of each line.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	// bol is whether the next byte begins a line.
+	bol bool
+}
+
+func (l *prefixWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		if l.bol {
+			if _, err := io.WriteString(l.w, l.prefix); err != nil {
+				return n, err
+			}
+			l.bol = false
+		}
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line = p[:i+1]
+			l.bol = true
 		}
-		return aint < bint
+		m, err := l.w.Write(line)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		p = p[len(line):]
+	}
+	return n, nil
+}
+
+var errTooLong = errors.New("too long")
+
+// runeLimitWriter writes to w,
+// returning errTooLong if more than n runes are written.
+type runeLimitWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *runeLimitWriter) Write(p []byte) (int, error) {
+	if l.n -= utf8.RuneCount(p); l.n < 0 {
+		return 0, errTooLong
+	}
+	return l.w.Write(p)
+}
+
+// printCompact prints v on a single line.
+func (p *printer) printCompact(v reflect.Value) {
+	compact := p.compact
+	p.compact = true
+	p.print("", v)
+	p.compact = compact
+}
+
+// line begins the ith element of a composite value,
+// on a new line at the given indent.
+// In compact mode, elements are instead separated by commas.
+func (p *printer) line(indent string, i int) {
+	switch {
+	case !p.compact:
+		p.pr("%s", indent)
+	case i > 0:
+		p.pr(", ")
+	}
+}
+
+// end ends a composite value with the closing delimiter,
+// on a new line at the given indent unless in compact mode.
+func (p *printer) end(indent, delim string) {
+	if p.compact {
+		indent = ""
+	}
+	p.pr("%s%s", indent, delim)
+}
+
+// compactString returns v printed on a single line.
+func (c *Config) compactString(v reflect.Value) string {
+	return (&traversal{c: c}).compactString(v)
+}
+
+// printStringValue prints the string value s,
+// scrubbed by ScrubString, or EmptyString if it is empty.
+func (p *printer) printStringValue(indent, s string) {
+	s = p.cfg.scrub(s)
+	if len(s) == 0 && p.cfg.EmptyString != "" {
+		p.pr("%s", p.cfg.EmptyString)
+	} else {
+		p.printString(indent, s)
+	}
+}
+
+// scrub returns s with ScrubString applied, if it is non-nil.
+func (c *Config) scrub(s string) string {
+	if c.ScrubString != nil {
+		return c.ScrubString(s)
+	}
+	return s
+}
+
+func (p *printer) printString(indent, s string) {
+	if p.cfg.MaxStringLen <= 0 || len(s) <= p.cfg.MaxStringLen {
+		if p.cfg.WrapWidth > 0 && !p.compact {
+			p.printWrapped(indent, s)
+		} else {
+			p.pr("%s", strconv.Quote(s))
+		}
+		return
+	}
+	n := p.cfg.MaxStringLen
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	q := strconv.Quote(s[:n])
+	p.pr("%s%s\" (+%d bytes)", q[:len(q)-1], p.cfg.TruncationMarker, len(s)-n)
+}
+
+// printWrapped prints s quoted, split into pieces joined by +,
+// with each piece after the first on a new line, indented,
+// so that no line is longer than WrapWidth columns, if possible.
+// Pieces end after a space, if there is one that fits.
+func (p *printer) printWrapped(indent, s string) {
+	width := p.cfg.WrapWidth - utf8.RuneCountInString(p.cfg.LinePrefix)
+	for {
+		// The quotes take two columns.
+		room := width - p.out.col - 2
+		if quotedWidth(s) <= room {
+			p.pr("%s", strconv.Quote(s))
+			return
+		}
+		// Leave room for the " +" after the piece.
+		room -= 2
+		var n, space, w int
+		for n < len(s) {
+			_, size := utf8.DecodeRuneInString(s[n:])
+			w += quotedWidth(s[n : n+size])
+			if w > room && n > 0 {
+				break
+			}
+			if s[n] == ' ' {
+				space = n + size
+			}
+			n += size
+		}
+		if space > 0 && n < len(s) {
+			n = space
+		}
+		p.pr("%s +%s", strconv.Quote(s[:n]), indent+p.cfg.Indent)
+		s = s[n:]
+	}
+}
+
+// quotedWidth returns the number of runes in s quoted, not counting the quotes.
+func quotedWidth(s string) int {
+	return utf8.RuneCountInString(strconv.Quote(s)) - 2
+}
+
+// A mapEntry is a key of a map and its value.
+//
+// Values are gathered with the keys, rather than by MapIndex,
+// since MapIndex cannot find the value of a NaN key.
+type mapEntry struct {
+	k, v reflect.Value
+}
+
+// sortEntries sorts map entries in place
+// in increasing order of their keys, and returns them.
+//
+// The order is total, so the result is the same on each call:
+// bools are ordered false < true, numbers and strings by their value,
+// with NaNs first, and complex numbers by their real then imaginary parts.
+// Keys of any other kind are ordered by their compactString.
+// Keys held in interfaces are ordered first by kind, then by type name,
+// then by value, with nil first.
+// Keys that are equal in this order, for example NaNs,
+// are ordered by the compactString of their map value.
+//
+// If MapSort is ByValue, the keys are ordered first by their map values,
+// in the same order.
+// If MapKeyLess is non-nil, it orders the keys,
+// and this order is used only for keys that are equal according to it.
+func (t *traversal) sortEntries(es []mapEntry) []mapEntry {
+	ks := &keySorter{t: t, entries: es}
+	ks.strs = make([]string, len(ks.entries))
+	ks.done = make([]bool, len(ks.entries))
+	sort.Sort(ks)
+	return ks.entries
+}
+
+type keySorter struct {
+	t       *traversal
+	entries []mapEntry
+	// strs caches the compactString of keys, if done.
+	strs []string
+	done []bool
+}
+
+func (ks *keySorter) Len() int { return len(ks.entries) }
+
+func (ks *keySorter) Swap(i, j int) {
+	ks.entries[i], ks.entries[j] = ks.entries[j], ks.entries[i]
+	ks.strs[i], ks.strs[j] = ks.strs[j], ks.strs[i]
+	ks.done[i], ks.done[j] = ks.done[j], ks.done[i]
+}
+
+func (ks *keySorter) Less(i, j int) bool {
+	if ks.t.c.MapSort == ByValue {
+		a, b := ks.entries[i].v, ks.entries[j].v
+		if c := ks.t.compareValues(a, b, func() (string, string) {
+			return ks.t.compactString(a), ks.t.compactString(b)
+		}); c != 0 {
+			return c < 0
+		}
+	}
+	if less := ks.t.c.MapKeyLess; less != nil {
+		a, b := ks.entries[i].k, ks.entries[j].k
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+	}
+	if c := ks.t.compareValues(ks.entries[i].k, ks.entries[j].k, func() (string, string) {
+		return ks.str(i), ks.str(j)
+	}); c != 0 {
+		return c < 0
+	}
+	return ks.t.compactString(ks.entries[i].v) < ks.t.compactString(ks.entries[j].v)
+}
+
+func (ks *keySorter) str(i int) string {
+	if !ks.done[i] {
+		ks.strs[i] = ks.t.compactString(ks.entries[i].k)
+		ks.done[i] = true
+	}
+	return ks.strs[i]
+}
+
+// compareValues returns -1, 0, or 1 if a is less than, equal to,
+// or greater than b in the order described by sortEntries.
+// strs returns the compactStrings of a and b.
+func (t *traversal) compareValues(a, b reflect.Value, strs func() (string, string)) int {
+	if a.Kind() == reflect.Interface || b.Kind() == reflect.Interface {
+		return t.compareInterfaces(a, b)
+	}
+	switch a.Kind() {
+	case reflect.Bool:
+		return compareInts(boolInt(a.Bool()), boolInt(b.Bool()))
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return a.Int() < b.Int()
+		return compareInts(a.Int(), b.Int())
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		return a.Uint() < b.Uint()
+		return compareUints(a.Uint(), b.Uint())
 
 	case reflect.Float32, reflect.Float64:
-		return a.Float() < b.Float()
+		return compareFloats(a.Float(), b.Float())
+
+	case reflect.Complex64, reflect.Complex128:
+		x, y := a.Complex(), b.Complex()
+		if d := compareFloats(real(x), real(y)); d != 0 {
+			return d
+		}
+		return compareFloats(imag(x), imag(y))
 
 	case reflect.String:
-		return a.String() < b.String()
+		return strings.Compare(a.String(), b.String())
 
 	default:
-		// Don't bother sorting any other kinds.
-		return false
+		x, y := strs()
+		return strings.Compare(x, y)
 	}
 }
 
-func pr(out io.Writer, f string, args ...interface{}) {
-	if _, err := fmt.Fprintf(out, f, args...); err != nil {
-		panic(err)
+// compareInterfaces compares values, at least one of which is an interface.
+func (t *traversal) compareInterfaces(a, b reflect.Value) int {
+	for a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	switch {
+	case !a.IsValid() || !b.IsValid():
+		return compareInts(boolInt(a.IsValid()), boolInt(b.IsValid()))
+	case a.Kind() != b.Kind():
+		return compareInts(int64(a.Kind()), int64(b.Kind()))
+	case a.Type() != b.Type():
+		return strings.Compare(a.Type().String(), b.Type().String())
+	}
+	return t.compareValues(a, b, func() (string, string) {
+		return t.compactString(a), t.compactString(b)
+	})
+}
+
+func compareInts(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareUints(x, y uint64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareFloats(x, y float64) int {
+	switch {
+	case math.IsNaN(x) || math.IsNaN(y):
+		return compareInts(boolInt(!math.IsNaN(x)), boolInt(!math.IsNaN(y)))
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
 	}
 }
 
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// panicError returns an error for a recovered panic value.
+// Values that are not errors, such as from a panicking PrettyPrint method,
+// are wrapped in an error.
+// A nil r, from panic(nil), is also returned as an error.
+func panicError(r interface{}) error {
+	if r == nil {
+		return errors.New("pretty: panic with a nil value")
+	}
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// pr prints to p.out.
+// Write errors are recorded by p.out, which ignores later writes.
+func (p *printer) pr(f string, args ...interface{}) {
+	fmt.Fprintf(p.out, f, args...)
+}
+
+// field returns the ith field of the struct v
+// and whether the field should be printed.
+func (c *Config) field(v reflect.Value, i int) (reflect.Value, bool) {
+	f := v.Type().Field(i)
+	if !c.visible(&f) {
+		return reflect.Value{}, false
+	}
+	if exported(&f) {
+		return v.Field(i), true
+	}
+	if !v.CanAddr() {
+		return reflect.Value{}, false
+	}
+	// Unexported fields cannot be used with Interface,
+	// so make a new Value from the field's address.
+	p := unsafe.Pointer(v.Field(i).UnsafeAddr())
+	return reflect.NewAt(f.Type, p).Elem(), true
+}
+
+// skip returns whether v is of a kind in SkipKinds,
+// or holds a value of such a kind in an interface.
+func (c *Config) skip(v reflect.Value) bool {
+	for _, k := range c.SkipKinds {
+		if v.Kind() == k || v.Kind() == reflect.Interface && v.Elem().Kind() == k {
+			return true
+		}
+	}
+	return false
+}
+
+// visible returns whether the struct field f should be printed.
+func (c *Config) visible(f *reflect.StructField) bool {
+	if c.FieldVisible != nil {
+		return c.FieldVisible(*f)
+	}
+	return exported(f) || c.ShowUnexported
+}
+
+// formatFloat returns f formatted with FloatFormat.
+func (c *Config) formatFloat(f float64) string {
+	format := c.FloatFormat
+	if format == "" {
+		format = "%f"
+	}
+	return fmt.Sprintf(format, c.canonical(f))
+}
+
+// formatComplex returns x formatted like a Go literal, such as 3+5i,
+// with each part formatted by formatFloat.
+// A zero real part is omitted, as in Go source, so 0+5i is printed as 5i.
+func (c *Config) formatComplex(x complex128) string {
+	im := c.formatFloat(imag(x)) + "i"
+	if real(x) == 0 {
+		return im
+	}
+	if im[0] != '+' && im[0] != '-' {
+		im = "+" + im
+	}
+	return c.formatFloat(real(x)) + im
+}
+
+// canonical returns the canonical form of f if CanonicalFloats is true,
+// and otherwise returns f.
+func (c *Config) canonical(f float64) float64 {
+	switch {
+	case !c.CanonicalFloats:
+		return f
+	case f == 0:
+		return 0
+	case math.IsNaN(f):
+		return math.NaN()
+	default:
+		return f
+	}
+}
+
+// byteSize returns a human-readable size of n bytes.
+func byteSize(n int) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%dB", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%dKB", n>>10)
+	default:
+		return fmt.Sprintf("%dMB", n>>20)
+	}
+}
+
+// hasTag returns whether the struct field's pretty tag contains opt.
+// The pretty tag is a comma-separated list of options.
+func hasTag(f reflect.StructField, opt string) bool {
+	for _, o := range strings.Split(f.Tag.Get("pretty"), ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
 func exported(f *reflect.StructField) bool {
 	r, _ := utf8.DecodeRuneInString(f.Name)
 	return unicode.IsUpper(r)
 }
 
+// An identity identifies a pointer, map, or slice by the memory it refers to.
+// Two values with the same identity share their referent;
+// two values that are merely equal do not.
+type identity struct {
+	t reflect.Type
+	p uintptr
+	// n is the length of a slice.
+	n int
+}
+
+// identify returns the identity of v
+// and whether v is a non-nil pointer, non-nil map, or non-empty slice,
+// the only kinds of values through which a cycle can pass.
+func identify(v reflect.Value) (identity, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map:
+		if v.IsNil() {
+			return identity{}, false
+		}
+		return identity{t: v.Type(), p: v.Pointer()}, true
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return identity{}, false
+		}
+		return identity{t: v.Type(), p: v.Pointer(), n: v.Len()}, true
+	default:
+		return identity{}, false
+	}
+}
+
+// isNamedScalar returns whether t is a named type
+// with a bool, numeric, or string underlying type,
+// other than the predeclared types themselves.
+func isNamedScalar(t reflect.Type) bool {
+	if t.Name() == "" || t.PkgPath() == "" {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNumber returns whether k is an integer, floating point, or complex kind.
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	default:
+		return false
+	}
+}
+
 func isEmpty(v reflect.Value) bool {
 	switch v.Kind() {
 	case reflect.Array, reflect.Slice, reflect.Map:
@@ -271,3 +1874,13 @@ func isComplex(v reflect.Value) bool {
 		return false
 	}
 }
+
+// hasComplexValues returns whether any of the map entries' values isComplex.
+func hasComplexValues(es []mapEntry) bool {
+	for _, e := range es {
+		if isComplex(e.v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1145 @@
+package pretty
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// typeCheck type-checks a Go source file, returning any error.
+func typeCheck(src string) error {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.go", src, 0)
+	if err != nil {
+		return err
+	}
+	var conf types.Config
+	_, err = conf.Check("p", fset, []*ast.File{f}, nil)
+	return err
+}
+
+func TestGoStringMapOfSlices(t *testing.T) {
+	v := map[string][]int{
+		"b": {3},
+		"a": {1, 2},
+		"c": {},
+		"d": nil,
+	}
+	got := GoString(v)
+	const want = `map[string][]int{"a": []int{1, 2}, "b": []int{3}, "c": []int{}, "d": nil}`
+	if got != want {
+		t.Errorf("GoString(%#v)=%s, want %s", v, got, want)
+	}
+	if err := typeCheck("package p\nvar _ map[string][]int = " + got); err != nil {
+		t.Errorf("GoString(%#v)=%s does not compile: %v", v, got, err)
+	}
+}
+
+func TestGoStringNested(t *testing.T) {
+	type T struct {
+		A    [2]map[string]int
+		B    []interface{}
+		C    *[]uint8
+		D, e int
+	}
+	v := T{
+		A: [2]map[string]int{{"x": 1}, nil},
+		B: []interface{}{1, int8(2), "three", 4.5, []int{5}, nil},
+		C: &[]uint8{6},
+	}
+	got := GoString(v)
+	const want = `pretty.T{` +
+		`A: [2]map[string]int{map[string]int{"x": 1}, nil}, ` +
+		`B: []interface {}{1, int8(2), "three", float64(4.5), []int{5}, nil}, ` +
+		`C: &[]uint8{6}, ` +
+		`D: 0}`
+	if got != want {
+		t.Errorf("GoString(%#v)=%s, want %s", v, got, want)
+	}
+}
+
+func TestFprintGoError(t *testing.T) {
+	for _, v := range []interface{}{
+		make(chan int),
+		func() {},
+		[]interface{}{func() {}},
+	} {
+		if err := FprintGo(new(bytes.Buffer), v); err == nil {
+			t.Errorf("FprintGo(%T) succeeded, want error", v)
+		}
+	}
+}
+
+func TestFprintGoNil(t *testing.T) {
+	type T struct {
+		A int
+		F func()
+		C chan int
+		P unsafe.Pointer
+	}
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{T{A: 1}, "pretty.T{A: 1, F: nil, C: nil, P: nil}"},
+		{[]interface{}{(func())(nil)}, "[]interface {}{(func())(nil)}"},
+	}
+	for _, test := range tests {
+		if got := GoString(test.v); got != test.want {
+			t.Errorf("GoString(%T)=%q, want %q", test.v, got, test.want)
+		}
+	}
+}
+
+func TestFprintGoWriteError(t *testing.T) {
+	w := countWriter{limit: 10}
+	if err := FprintGo(&w, make([]int, 100)); err == nil || err.Error() != "limit reached" {
+		t.Errorf("FprintGo(…)=%v, want limit reached", err)
+	}
+	if w.n != 10 {
+		t.Errorf("FprintGo(…) wrote %d bytes, want 10", w.n)
+	}
+}
+
+func TestRendererSchema(t *testing.T) {
+	type T struct {
+		A   int
+		b   string
+		Pos struct{ X, Y int }
+	}
+	r := With(Config{Indent: "  ", ShowUnexported: true})
+	const want = "T{\n  A: int\n  b: string\n  Pos: struct {\n    X: int\n    Y: int\n  }\n}"
+	if got := r.Schema(T{}); got != want {
+		t.Errorf("Schema(T{})=%q, want %q", got, want)
+	}
+	w := countWriter{limit: 10}
+	if err := r.FprintSchema(&w, T{}); err == nil || err.Error() != "limit reached" {
+		t.Errorf("FprintSchema(…)=%v, want limit reached", err)
+	}
+}
+
+func TestSchemaRecursive(t *testing.T) {
+	type L []L
+	type M map[string]M
+	type T struct {
+		L L
+		M M
+	}
+	const want = "T{\n\tL: []L\n\tM: map[string]M\n}"
+	if got := Schema(T{}); got != want {
+		t.Errorf("Schema(T{})=%q, want %q", got, want)
+	}
+}
+
+func TestSummaryKB(t *testing.T) {
+	orig := Summary
+	Summary = true
+	defer func() { Summary = orig }()
+
+	// Each element prints as "\n\t0", 3 bytes,
+	// plus 3 bytes for the brackets and the newline before "]".
+	s := String(make([]int, 2048))
+	const want = "\n# 2049 nodes, 6KB"
+	if !strings.HasSuffix(s, want) {
+		t.Errorf("String(make([]int, 2048)) ends with %q, want %q", s[strings.LastIndex(s, "\n"):], want)
+	}
+}
+
+func TestStatInline(t *testing.T) {
+	type Point struct{ X, Y int }
+	type T struct {
+		Name string
+		Xs   []int
+		P    Point
+	}
+	v := T{Name: "a long name", Xs: []int{1, 2, 3}, P: Point{X: 1, Y: 2}}
+	for _, cfg := range []Config{{}, {InlineUnder: 20}, {Width: 30}} {
+		if _, nodes, _ := With(cfg).Stat(v); nodes != 9 {
+			t.Errorf("Stat(…) with InlineUnder=%d, Width=%d: nodes=%d, want 9", cfg.InlineUnder, cfg.Width, nodes)
+		}
+		cfg.Summary = true
+		const want = "# 9 nodes,"
+		if s := With(cfg).String(v); !strings.Contains(s, want) {
+			t.Errorf("String(…) with InlineUnder=%d, Width=%d: %q, want it to contain %q", cfg.InlineUnder, cfg.Width, s, want)
+		}
+	}
+}
+
+func TestSummaryBytes(t *testing.T) {
+	type T struct{ A, B []int }
+	v := T{A: []int{1, 2, 3}, B: make([]int, 8)}
+	for _, cfg := range []Config{
+		{Summary: true},
+		{Summary: true, LinePrefix: "> "},
+		{Summary: true, LineNumbers: true},
+		{Summary: true, LinePrefix: "> ", LineNumbers: true},
+	} {
+		s := With(cfg).String(v)
+		i := strings.LastIndex(s, "\n")
+		if want := fmt.Sprintf(" nodes, %dB", i); !strings.HasSuffix(s, want) {
+			t.Errorf("LinePrefix=%q, LineNumbers=%v: %q, want it to end with %q", cfg.LinePrefix, cfg.LineNumbers, s, want)
+		}
+	}
+}
+
+func TestCanonicalFloats(t *testing.T) {
+	orig := CanonicalFloats
+	CanonicalFloats = true
+	defer func() { CanonicalFloats = orig }()
+
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{math.Copysign(0, -1), "0.000000"},
+		{float32(math.Copysign(0, -1)), "0.000000"},
+		{math.Float64frombits(0x7ff0000000000001), "NaN"}, // signaling
+		{math.Float64frombits(0x7ff8000000000001), "NaN"}, // quiet
+		{math.Float64frombits(0xfff8000000000000), "NaN"}, // negative
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{complex(math.Copysign(0, -1), math.Copysign(0, -1)), "0.000000i"},
+	}
+	for _, test := range tests {
+		if got := String(test.v); got != test.want {
+			t.Errorf("String(%v)=%q, want %q", test.v, got, test.want)
+		}
+	}
+}
+
+func TestMapOrderIsDeterministic(t *testing.T) {
+	type K struct {
+		X int
+		Y string
+	}
+	tests := []interface{}{
+		map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5},
+		map[float64]int{math.NaN(): 1, math.NaN(): 2, math.Inf(-1): 3, 0: 4, math.Inf(1): 5},
+		map[complex128]int{1 + 2i: 1, 1 + 1i: 2, 2: 3, 0: 4},
+		map[[2]int]int{{1, 2}: 1, {2, 1}: 2, {1, 1}: 3, {0, 5}: 4},
+		map[K]int{{1, "a"}: 1, {1, "b"}: 2, {0, "z"}: 3, {2, ""}: 4},
+		map[*int]int{new(int): 1, new(int): 2, new(int): 3},
+		map[interface{}]int{nil: 0, 1: 1, "1": 2, 1.5: 3, uint(1): 4, K{}: 5, [1]int{}: 6, false: 7},
+	}
+	for _, v := range tests {
+		want := String(v)
+		for i := 0; i < 100; i++ {
+			if got := String(v); got != want {
+				t.Fatalf("String(%#v)=\n%s\nthen\n%s", v, want, got)
+			}
+		}
+	}
+}
+
+// The values of NaN keys, which MapIndex cannot find, are printed.
+func TestNaNMapKeys(t *testing.T) {
+	v := map[float64]int{math.NaN(): 1, math.NaN(): 2, 0: 3}
+	const want = "{\n\tNaN: 1\n\tNaN: 2\n\t0.000000: 3\n}"
+	if got := String(v); got != want {
+		t.Errorf("String(%v)=%q, want %q", v, got, want)
+	}
+}
+
+// countWriter counts calls to Write, and fails after limit bytes if limit > 0.
+type countWriter struct {
+	writes, n, limit int
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.limit > 0 && w.n+len(p) > w.limit {
+		n := w.limit - w.n
+		w.n = w.limit
+		return n, errors.New("limit reached")
+	}
+	w.n += len(p)
+	return len(p), nil
+}
+
+func TestFprintBuffers(t *testing.T) {
+	var w countWriter
+	if err := Fprint(&w, make([]int, 100)); err != nil {
+		t.Fatalf("Fprint(…)=%v", err)
+	}
+	if w.writes != 1 {
+		t.Errorf("Fprint(…) made %d writes, want 1", w.writes)
+	}
+	if want := len(String(make([]int, 100))); w.n != want {
+		t.Errorf("Fprint(…) wrote %d bytes, want %d", w.n, want)
+	}
+}
+
+func TestFprintFlushError(t *testing.T) {
+	w := countWriter{limit: 10}
+	if err := Fprint(&w, make([]int, 100)); err == nil {
+		t.Errorf("Fprint(…)=nil, want error")
+	}
+	if w.n != 10 {
+		t.Errorf("Fprint(…) wrote %d bytes, want 10", w.n)
+	}
+}
+
+type panicPrinter struct{}
+
+func (panicPrinter) PrettyPrint() string { panic("PrettyPrint failed") }
+
+func TestFprintNonErrorPanic(t *testing.T) {
+	err := Fprint(new(bytes.Buffer), []interface{}{1, panicPrinter{}})
+	if err == nil || err.Error() != "PrettyPrint failed" {
+		t.Errorf("Fprint(…)=%v, want PrettyPrint failed", err)
+	}
+}
+
+type (
+	formattedA int
+	formattedB int
+)
+
+func TestRegisterFormatter(t *testing.T) {
+	a := reflect.TypeOf(formattedA(0))
+	b := reflect.TypeOf(formattedB(0))
+	defer RegisterFormatter(a, nil)
+	defer RegisterFormatter(b, nil)
+
+	// Simulate two packages registering from their init functions.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		RegisterFormatter(a, func(v reflect.Value) string {
+			return fmt.Sprintf("A(%d)", v.Int())
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		RegisterFormatter(b, func(v reflect.Value) string {
+			return fmt.Sprintf("B(%d)", v.Int())
+		})
+	}()
+	wg.Wait()
+
+	got := String([]interface{}{formattedA(1), formattedB(2)})
+	const want = "[\n\tA(1)\n\tB(2)\n]"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	RegisterFormatter(a, func(reflect.Value) string { return "last" })
+	if got := String(formattedA(1)); got != "last" {
+		t.Errorf("got %q, want %q", got, "last")
+	}
+
+	RegisterFormatter(a, nil)
+	if got := String(formattedA(1)); got != "1" {
+		t.Errorf("got %q, want %q", got, "1")
+	}
+}
+
+func TestRegisterUnionRemove(t *testing.T) {
+	type T struct{ P isPayload }
+	u := reflect.TypeOf((*isPayload)(nil)).Elem()
+	v := T{P: Delay(10)}
+	RegisterUnion(u, true)
+	defer RegisterUnion(u, false)
+	if got, want := String(v), "T{P = Delay(10)}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	RegisterUnion(u, false)
+	if got, want := String(v), "T{P: 10}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFprintContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := FprintContext(ctx, &buf, make([]int, 10*checkEvery)); err != context.Canceled {
+		t.Errorf("FprintContext(…)=%v, want %v", err, context.Canceled)
+	}
+	if full := len(String(make([]int, 10*checkEvery))); buf.Len() >= full {
+		t.Errorf("FprintContext(…) wrote %d bytes, want fewer than %d", buf.Len(), full)
+	}
+}
+
+func TestFprintContextCancelledSmall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := FprintContext(ctx, &buf, []int{1, 2}); err != context.Canceled {
+		t.Errorf("FprintContext(…)=%v, want %v", err, context.Canceled)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("FprintContext(…) wrote %q, want nothing", buf.String())
+	}
+
+	// The context is checked again once printing is done.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	if err := FprintContext(ctx, new(bytes.Buffer), cancelPrinter{cancel}); err != context.Canceled {
+		t.Errorf("FprintContext(…)=%v, want %v", err, context.Canceled)
+	}
+}
+
+// A cancelPrinter cancels a context when it is printed.
+type cancelPrinter struct{ cancel func() }
+
+func (p cancelPrinter) PrettyPrint() string {
+	p.cancel()
+	return "cancelled"
+}
+
+func TestFprintContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	err := FprintContext(ctx, new(bytes.Buffer), make([]int, 10*checkEvery))
+	if err != context.DeadlineExceeded {
+		t.Errorf("FprintContext(…)=%v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	type T struct {
+		Name string
+		Tags map[string]int
+		Next *T
+	}
+	v := &T{Name: "<a&b>", Tags: map[string]int{"x": 1}}
+	v.Next = v
+	got, err := HTML(v)
+	if err != nil {
+		t.Fatalf("HTML(…)=_, %v", err)
+	}
+	const want = `<div class="entry"><details open><summary><span class="type">T</span></summary>` +
+		`<div class="entry"><span class="name">Name</span>: <span class="value">&#34;&lt;a&amp;b&gt;&#34;</span></div>` +
+		`<div class="entry"><span class="name">Tags</span>: <details open><summary><span class="type">map[string]int</span></summary>` +
+		`<div class="entry"><span class="name">&#34;x&#34;</span>: <span class="value">1</span></div>` +
+		`</details></div>` +
+		`<div class="entry"><span class="name">Next</span>: <span class="cycle">&lt;cycle&gt;</span></div>` +
+		`</details></div>`
+	if string(got) != want {
+		t.Errorf("HTML(…)=\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRendererHTML(t *testing.T) {
+	type T struct {
+		a    int
+		Next *T
+	}
+	v := &T{a: 1}
+	v.Next = v
+	got, err := With(Config{ShowUnexported: true, CyclePlaceholder: "@"}).HTML(v)
+	if err != nil {
+		t.Fatalf("HTML(…)=_, %v", err)
+	}
+	const want = `<div class="entry"><details open><summary><span class="type">T</span></summary>` +
+		`<div class="entry"><span class="name">a</span>: <span class="value">1</span></div>` +
+		`<div class="entry"><span class="name">Next</span>: <span class="cycle">@</span></div>` +
+		`</details></div>`
+	if string(got) != want {
+		t.Errorf("HTML(…)=\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHTMLError(t *testing.T) {
+	if _, err := HTML(panicPrinter{}); err == nil {
+		t.Errorf("HTML(panicPrinter{}) succeeded, want error")
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	orig := MaxOutputBytes
+	defer func() { MaxOutputBytes = orig }()
+
+	type T struct{ A, B string }
+	v := T{A: "αβγ", B: "xyz"}
+	// T{\n\tA: "αβγ"\n\tB: "xyz"\n}
+	full := String(v)
+
+	MaxOutputBytes = len(full)
+	if got, err := StringErr(v); err != nil || got != full {
+		t.Errorf("MaxOutputBytes=len: got %q, %v, want %q, nil", got, err, full)
+	}
+
+	// The 11th byte is in the middle of the 2-byte β.
+	MaxOutputBytes = 11
+	const want = "T{\n\tA: \"α… (truncated)"
+	if got, err := StringErr(v); err != ErrTruncated || got != want {
+		t.Errorf("MaxOutputBytes=11: got %q, %v, want %q, %v", got, err, want, ErrTruncated)
+	}
+
+	// Line prefixes and numbers count toward the limit.
+	MaxOutputBytes = 0
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{MaxOutputBytes: 11, LinePrefix: "> "}, "> T{\n> \tA: … (truncated)"},
+		{Config{MaxOutputBytes: 11, LineNumbers: true}, "1| T{\n2| \tA… (truncated)"},
+	}
+	for _, test := range tests {
+		got, err := With(test.cfg).StringErr(v)
+		if err != ErrTruncated || got != test.want {
+			t.Errorf("%+v: got %q, %v, want %q, %v", test.cfg, got, err, test.want, ErrTruncated)
+		}
+	}
+
+	// Numbered output stops being printed once it is over the limit.
+	r := With(Config{MaxOutputBytes: 100, LineNumbers: true})
+	var n int
+	vs := make([]printCounter, 10000)
+	for i := range vs {
+		vs[i].n = &n
+	}
+	if err := r.Fprint(new(bytes.Buffer), vs); err != ErrTruncated || n > 100 {
+		t.Errorf("Fprint(…)=%v after %d values, want %v after at most 100", err, n, ErrTruncated)
+	}
+}
+
+// A printCounter counts the calls of its PrettyPrint method.
+type printCounter struct{ n *int }
+
+func (p printCounter) PrettyPrint() string {
+	*p.n++
+	return "x"
+}
+
+func TestWithZeroConfig(t *testing.T) {
+	type T struct {
+		A []int
+		B map[string]*T
+		C string
+	}
+	v := &T{A: []int{1, 2}, B: map[string]*T{"x": {C: "y"}}}
+	v.B["self"] = v
+	want := String(v)
+
+	// Package-level options do not affect a Renderer.
+	origIndent, origStringer := Indent, UseStringer
+	Indent, UseStringer = "    ", true
+	defer func() { Indent, UseStringer = origIndent, origStringer }()
+	if got := With(Config{}).String(v); got != want {
+		t.Errorf("With(Config{}).String(v)=%q, want %q", got, want)
+	}
+}
+
+// A failed attempt to print a value inline
+// does not number the shared pointers within it.
+func TestDedupPointersInline(t *testing.T) {
+	type T struct {
+		Name string
+		P    *int
+	}
+	n := 5
+	v := []T{{Name: "a long name that does not fit", P: &n}, {Name: "b", P: &n}}
+	got := With(Config{DedupPointers: true, InlineUnder: 30}).String(v)
+	const want = "[\n\tT{\n\t\tName: \"a long name that does not fit\"\n\t\tP: #1 5\n\t}\n\tT{Name: \"b\", P: ↺ #1}\n]"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDedupPointersMap(t *testing.T) {
+	type T struct{ N int }
+	type Pool struct {
+		ByName  map[string]*T
+		Default *T
+	}
+	p, q := &T{N: 1}, &T{N: 2}
+	v := Pool{ByName: map[string]*T{"a": p, "b": p, "c": q}, Default: q}
+	got := With(Config{DedupPointers: true}).String(v)
+	const want = "Pool{\n\tByName: {\n\t\t\"a\": #1 T{N: 1}\n\t\t\"b\": ↺ #1\n\t\t\"c\": #2 T{N: 2}\n\t}\n\tDefault: ↺ #2\n}"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDedupPointersMaxFields(t *testing.T) {
+	type T struct{ A, B *int }
+	n := 5
+	got := With(Config{DedupPointers: true, MaxFields: 1}).String(T{A: &n, B: &n})
+	const want = "T{\n\tA: 5\n\t… (+1 more)\n}"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDedupPointersPrintPath(t *testing.T) {
+	type T struct{ A *int }
+	n := 5
+	var buf bytes.Buffer
+	if err := With(Config{DedupPointers: true}).FprintPath(&buf, []T{{A: &n}, {A: &n}}, "[0]"); err != nil {
+		t.Fatalf("FprintPath(…)=%v", err)
+	}
+	const want = "[\n\t0: T{A: 5}\n\t…\n]"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestShowPointers(t *testing.T) {
+	r := With(Config{ShowPointers: true})
+	x := 5
+	ptr := unsafe.Pointer(&x)
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{uintptr(0xc000123450), "0xc000123450"},
+		{uintptr(0), "0x0"},
+		{ptr, fmt.Sprintf("%#x", uintptr(ptr))},
+		{unsafe.Pointer(nil), "nil"},
+		{uint(255), "255"},
+	}
+	for _, test := range tests {
+		if got := r.String(test.v); got != test.want {
+			t.Errorf("String(%v)=%q, want %q", test.v, got, test.want)
+		}
+	}
+}
+
+func TestRawStringer(t *testing.T) {
+	type T struct {
+		D time.Duration
+		C celsius
+	}
+	v := T{D: 1500 * time.Millisecond, C: 21.5}
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{}, "T{\n\tD: 1500000000\n\tC: 21.500000\n}"},
+		{Config{UseStringer: true}, "T{\n\tD: 1.5s\n\tC: 21.5°C\n}"},
+		{Config{UseStringer: true, RawStringer: true}, "T{\n\tD: 1500000000\n\tC: 21.500000\n}"},
+		{Config{RawStringer: true}, "T{\n\tD: 1500000000\n\tC: 21.500000\n}"},
+	}
+	for _, test := range tests {
+		if got := With(test.cfg).String(v); got != test.want {
+			t.Errorf("With(%+v).String(v)=%q, want %q", test.cfg, got, test.want)
+		}
+	}
+}
+
+// countedText counts the calls to its MarshalText method.
+type countedText struct{ n *int }
+
+func (c countedText) String() string { return "string" }
+
+func (c countedText) MarshalText() ([]byte, error) {
+	*c.n++
+	return []byte("text"), nil
+}
+
+func TestTextMarshalerCalledOnlyIfUsed(t *testing.T) {
+	type T struct{ A, B countedText }
+	var n int
+	v := T{countedText{&n}, countedText{&n}}
+	r := With(Config{UseStringer: true, UseTextMarshaler: true})
+	if got, want := r.String(v), "T{\n\tA: string\n\tB: string\n}"; got != want || n != 0 {
+		t.Errorf("got %q with %d MarshalText calls, want %q with 0", got, n, want)
+	}
+	r = With(Config{UseTextMarshaler: true, ShowTopType: true})
+	if got, want := r.String(v), "T{\n\tA: \"text\"\n\tB: \"text\"\n}"; got != want || n != 2 {
+		t.Errorf("got %q with %d MarshalText calls, want %q with 2", got, n, want)
+	}
+	n = 0
+	if got, want := r.String(v.A), `"text"`; got != want || n != 1 {
+		t.Errorf("got %q with %d MarshalText calls, want %q with 1", got, n, want)
+	}
+}
+
+func TestResetDefaults(t *testing.T) {
+	// The package-level options match the zero Config
+	// both before and after ResetDefaults.
+	want := With(Config{}).cfg
+	if got := globalConfig(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("globalConfig()=%+v, want %+v", got, want)
+	}
+	Indent, CyclePlaceholder, MaxStringLen, UseStringer, Sparse = "  ", "", 3, true, true
+	ResetDefaults()
+	if got := globalConfig(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after ResetDefaults, globalConfig()=%+v, want %+v", got, want)
+	}
+}
+
+func TestPrintPathErrors(t *testing.T) {
+	type T struct {
+		A []int
+		B *T
+		c int
+	}
+	v := T{A: []int{1}}
+	for _, path := range []string{"C", "c", "A[1]", "B.A", "A.X", "A[0].X"} {
+		var buf bytes.Buffer
+		if err := With(Config{}).FprintPath(&buf, v, path); err == nil || buf.Len() > 0 {
+			t.Errorf("FprintPath(%q) printed %q, returned %v, want an error", path, buf.String(), err)
+		}
+	}
+	for _, path := range []string{"", ".", "A[", "A[x]", "A[-1]", "A.[0]", "A]", "A..B"} {
+		if err := With(Config{}).FprintPath(new(bytes.Buffer), v, path); err == nil {
+			t.Errorf("FprintPath(%q)=nil, want an error", path)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := With(Config{IgnoreMissingPaths: true}).FprintPath(&buf, v, "C", "A[0]")
+	if want := "T{\n\tA: [\n\t\t0: 1\n\t]\n}"; err != nil || buf.String() != want {
+		t.Errorf("IgnoreMissingPaths: got %q, %v, want %q, nil", buf.String(), err, want)
+	}
+}
+
+func TestNewline(t *testing.T) {
+	type T struct {
+		A []int
+		M matrix
+	}
+	r := With(Config{Newline: "\r\n", Summary: true})
+	got := r.String(T{A: []int{1}, M: matrix{{1, 2}, {3, 4}}})
+	const want = "T{\r\n\tA: [\r\n\t\t1\r\n\t]\r\n\tM: |1 2|\r\n\t   |3 4|\r\n}\r\n# 4 nodes, 43B"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{LineNumbers: true}, "1| [\n2| \t1\n3| \t2\n4| ]"},
+		{Config{LineNumbers: true, LinePrefix: "> "}, "1| > [\n2| > \t1\n3| > \t2\n4| > ]"},
+		{Config{LineNumbers: true, Newline: "\r\n"}, "1| [\r\n2| \t1\r\n3| \t2\r\n4| ]"},
+	}
+	for _, test := range tests {
+		if got := With(test.cfg).String([]int{1, 2}); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.cfg, got, test.want)
+		}
+	}
+}
+
+func TestPanicNil(t *testing.T) {
+	if err := Fprint(new(bytes.Buffer), fuzzPanicker{}); err == nil {
+		t.Errorf("Fprint(fuzzPanicker{})=nil, want an error")
+	}
+	if _, err := HTML(fuzzPanicker{}); err == nil {
+		t.Errorf("HTML(fuzzPanicker{})=nil, want an error")
+	}
+}
+
+func TestNullWrappers(t *testing.T) {
+	type Opt struct {
+		Valid bool
+		N     int
+	}
+	type Three struct {
+		String string
+		Valid  bool
+		Extra  int
+	}
+	type Row struct {
+		Name sql.NullString
+		Age  sql.NullInt64
+	}
+	r := With(Config{NullWrappers: true})
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{sql.NullString{String: "x", Valid: true}, `"x"`},
+		{sql.NullString{String: "x"}, "null"},
+		{sql.NullInt64{Int64: 5, Valid: true}, "5"},
+		{sql.NullBool{}, "null"},
+		{Opt{Valid: true, N: 3}, "3"},
+		{Opt{N: 3}, "null"},
+		{Three{String: "x", Valid: true}, "Three{\n\tString: \"x\"\n\tValid: true\n\tExtra: 0\n}"},
+		{Row{Name: sql.NullString{String: "ann", Valid: true}}, "Row{\n\tName: \"ann\"\n\tAge: null\n}"},
+	}
+	for _, test := range tests {
+		if got := r.String(test.v); got != test.want {
+			t.Errorf("String(%#v)=%q, want %q", test.v, got, test.want)
+		}
+	}
+	const want = "NullString{\n\tString: \"x\"\n\tValid: true\n}"
+	if got := String(sql.NullString{String: "x", Valid: true}); got != want {
+		t.Errorf("without NullWrappers, got %q, want %q", got, want)
+	}
+}
+
+func TestCSVErrors(t *testing.T) {
+	for _, v := range []interface{}{nil, 5, "abc", []int{1}, map[string]int{}, struct{ A []int }{}} {
+		if s, err := CSV(v); err == nil {
+			t.Errorf("CSV(%#v)=%q, nil, want an error", v, s)
+		}
+	}
+}
+
+func TestMapKeyLess(t *testing.T) {
+	v := map[string]int{"b": 1, "C": 2, "a": 3, "A": 4, "c": 5}
+	r := With(Config{MapKeyLess: func(a, b reflect.Value) bool {
+		return strings.ToLower(a.String()) < strings.ToLower(b.String())
+	}})
+	const want = "{\n\t\"A\": 4\n\t\"a\": 3\n\t\"b\": 1\n\t\"C\": 2\n\t\"c\": 5\n}"
+	for i := 0; i < 10; i++ {
+		if got := r.String(v); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	type T struct {
+		A []int
+		B map[string]*T
+		C string
+	}
+	v := &T{A: []int{1, 2}, B: map[string]*T{"x": {C: "αβγ"}}}
+	v.B["self"] = v
+	if got, want := EstimateSize(v), len(String(v)); got != want {
+		t.Errorf("EstimateSize(v)=%d, want %d", got, want)
+	}
+	r := With(Config{Indent: "  ", LinePrefix: "> "})
+	if got, want := r.EstimateSize(v), len(r.String(v)); got != want {
+		t.Errorf("r.EstimateSize(v)=%d, want %d", got, want)
+	}
+}
+
+func TestScrubString(t *testing.T) {
+	type User struct {
+		Name  string
+		Email string
+	}
+	type Team struct {
+		Lead    User
+		Members map[string]User
+	}
+	email := regexp.MustCompile(`[\w.]+@[\w.]+`)
+	r := With(Config{ScrubString: func(s string) string {
+		return email.ReplaceAllString(s, "<email>")
+	}})
+	v := Team{
+		Lead:    User{Name: "ann", Email: "ann@example.com"},
+		Members: map[string]User{"bob@example.com": {Name: "bob", Email: "contact: bob@example.com"}},
+	}
+	got := r.String(v)
+	const want = "Team{\n" +
+		"\tLead: User{\n\t\tName: \"ann\"\n\t\tEmail: \"<email>\"\n\t}\n" +
+		"\tMembers: {\n\t\t\"<email>\": User{\n\t\t\tName: \"bob\"\n\t\t\tEmail: \"contact: <email>\"\n\t\t}\n\t}\n" +
+		"}"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	type Node struct {
+		Pass []rune
+		Next map[string]*Node
+	}
+	r = With(Config{
+		ScrubString:        func(s string) string { return strings.ReplaceAll(s, "password", "***") },
+		RuneSlicesAsString: true,
+		VerboseCycles:      true,
+	})
+	m := &Node{}
+	m.Next = map[string]*Node{"loop": m}
+	n := &Node{Pass: []rune("password"), Next: map[string]*Node{"password": m}}
+	got = r.String(n)
+	const want2 = "Node{\n\tPass: \"***\"\n\tNext: {\n\t\t\"***\": Node{\n\t\t\tNext: {\n\t\t\t\t\"loop\": <cycle -> /Next/***>\n\t\t\t}\n\t\t}\n\t}\n}"
+	if got != want2 {
+		t.Errorf("got %q, want %q", got, want2)
+	}
+}
+
+// A []interface{} holding itself, directly or through other values,
+// is a cycle, even though each element is boxed in an interface.
+func TestInterfaceSliceCycle(t *testing.T) {
+	s := []interface{}{1, nil}
+	s[1] = s
+	a := []interface{}{nil}
+	a[0] = map[string]interface{}{"a": []interface{}{a}}
+	sub := make([]interface{}, 2)
+	sub[0] = sub[:1]
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{s, "[\n\t1\n\t<cycle>\n]"},
+		{a, "[\n\t{\n\t\t\"a\": [\n\t\t\t<cycle>\n\t\t]\n\t}\n]"},
+		{sub, "[\n\t[\n\t\t<cycle>\n\t]\n\tnil\n]"},
+	}
+	for _, test := range tests {
+		if got := String(test.v); got != test.want {
+			t.Errorf("String(...)=%q, want %q", got, test.want)
+		}
+		if got := With(Config{DedupPointers: true, InlineUnder: 80}).String(test.v); got == "" {
+			t.Errorf("DedupPointers: String(...) is empty")
+		}
+		if _, err := HTML(test.v); err != nil {
+			t.Errorf("HTML(...)=%v", err)
+		}
+		if err := FprintGo(new(bytes.Buffer), test.v); err == nil {
+			t.Errorf("FprintGo(...)=nil, want an error")
+		}
+	}
+}
+
+func TestShowEmbeddedInterfaces(t *testing.T) {
+	type Stream struct {
+		io.Reader
+		io.Writer
+		Name string
+	}
+	r := With(Config{ShowEmbeddedInterfaces: true})
+	tests := []struct {
+		v    Stream
+		want string
+	}{
+		{
+			Stream{Name: "s"},
+			"Stream{\n\tReader: <nil io.Reader>\n\tWriter: <nil io.Writer>\n\tName: \"s\"\n}",
+		},
+		{
+			Stream{Reader: strings.NewReader(""), Name: "s"},
+			"Stream{\n\tReader: io.Reader(*strings.Reader) Reader{}\n\tWriter: <nil io.Writer>\n\tName: \"s\"\n}",
+		},
+	}
+	for _, test := range tests {
+		if got := r.String(test.v); got != test.want {
+			t.Errorf("String(%#v)=%q, want %q", test.v, got, test.want)
+		}
+	}
+	if got, want := String(Stream{Name: "s"}), "Stream{Name: \"s\"}"; got != want {
+		t.Errorf("without ShowEmbeddedInterfaces, got %q, want %q", got, want)
+	}
+}
+
+// ptrPrinter has a PrettyPrint method with a pointer receiver.
+type ptrPrinter struct{ n int }
+
+func (p *ptrPrinter) PrettyPrint() string { return fmt.Sprintf("<%d>", p.n) }
+
+// celsiusPtr has a String method with a pointer receiver.
+type celsiusPtr float64
+
+func (c *celsiusPtr) String() string { return fmt.Sprintf("%g°C", float64(*c)) }
+
+func TestPointerReceiverMethods(t *testing.T) {
+	type T struct {
+		P  ptrPrinter
+		Ps []ptrPrinter
+		M  map[string]ptrPrinter
+		D  celsiusPtr
+	}
+	v := T{
+		P:  ptrPrinter{1},
+		Ps: []ptrPrinter{{2}},
+		M:  map[string]ptrPrinter{"x": {3}},
+		D:  21.5,
+	}
+	r := With(Config{UseStringer: true})
+	const want = "T{\n\tP: <1>\n\tPs: [\n\t\t<2>\n\t]\n\tM: {\n\t\t\"x\": <3>\n\t}\n\tD: 21.5°C\n}"
+	// Fields of v are not addressable; fields of &v are.
+	for _, x := range []interface{}{v, &v} {
+		if got := r.String(x); got != want {
+			t.Errorf("String(%T)=%q, want %q", x, got, want)
+		}
+	}
+	if got := String(ptrPrinter{4}); got != "<4>" {
+		t.Errorf("String(ptrPrinter{4})=%q, want %q", got, "<4>")
+	}
+}
+
+// A getterCounter counts the calls of its getter.
+type getterCounter struct{ n *int }
+
+func (g getterCounter) Calls() int {
+	*g.n++
+	return *g.n
+}
+
+func TestCallGettersOnce(t *testing.T) {
+	type T struct{ G getterCounter }
+	// Width and DedupPointers each look at the value before it is printed.
+	r := With(Config{CallGetters: true, DedupPointers: true, Width: 80})
+	var n int
+	v := T{G: getterCounter{n: &n}}
+	for _, x := range []interface{}{v, &v} {
+		n = 0
+		got := r.String(x)
+		if n != 1 {
+			t.Errorf("String(%T) called the getter %d times, want 1", x, n)
+		}
+		if want := "T{G: getterCounter{Calls() -> 1}}"; got != want {
+			t.Errorf("String(%T)=%q, want %q", x, got, want)
+		}
+	}
+}
+
+// A fuzzPanicker panics with its value from PrettyPrint.
+type fuzzPanicker struct{ v interface{} }
+
+func (p fuzzPanicker) PrettyPrint() string { panic(p.v) }
+
+// A fuzzValue builds a value from the bytes of a fuzz input.
+type fuzzValue struct {
+	data     []byte
+	panicked bool
+	ptrs     []*interface{}
+}
+
+func (f *fuzzValue) byte() byte {
+	if len(f.data) == 0 {
+		return 0
+	}
+	b := f.data[0]
+	f.data = f.data[1:]
+	return b
+}
+
+func (f *fuzzValue) value(depth int) interface{} {
+	b := f.byte()
+	if depth > 4 {
+		b %= 8
+	}
+	switch b % 20 {
+	case 0:
+		return nil
+	case 1:
+		return int(int8(f.byte()))
+	case 2:
+		return string(f.data[:len(f.data)/2])
+	case 3:
+		return f.byte()%2 == 0
+	case 4:
+		return []float64{math.NaN(), math.Inf(1), math.Inf(-1), -0.0}[f.byte()%4]
+	case 5:
+		return complex(math.NaN(), float64(f.byte()))
+	case 6:
+		return uintptr(f.byte())
+	case 7:
+		return (*int)(nil)
+	case 8:
+		var s []interface{}
+		for n := f.byte() % 5; n > 0; n-- {
+			s = append(s, f.value(depth+1))
+		}
+		return s
+	case 9:
+		m := make(map[interface{}]interface{})
+		for n := f.byte() % 5; n > 0; n-- {
+			// Keys are distinct, so no value is lost.
+			switch f.byte() % 4 {
+			case 0:
+				m[len(m)] = f.value(depth + 1)
+			case 1:
+				m[string(rune('a'+len(m)))] = f.value(depth + 1)
+			case 2:
+				m[math.NaN()] = f.value(depth + 1)
+			default:
+				m[[2]interface{}{len(m), nil}] = f.value(depth + 1)
+			}
+		}
+		return m
+	case 10:
+		x := f.value(depth + 1)
+		f.ptrs = append(f.ptrs, &x)
+		return &x
+	case 11:
+		if len(f.ptrs) == 0 {
+			return nil
+		}
+		// A pointer to an enclosing value, making a cycle.
+		return f.ptrs[int(f.byte())%len(f.ptrs)]
+	case 12:
+		t := reflect.StructOf([]reflect.StructField{
+			{Name: "A", Type: reflect.TypeOf((*interface{})(nil)).Elem()},
+			{Name: "B", Type: reflect.TypeOf(0), Tag: `pretty:"raw"`},
+		})
+		s := reflect.New(t).Elem()
+		if x := f.value(depth + 1); x != nil {
+			s.Field(0).Set(reflect.ValueOf(x))
+		}
+		s.Field(1).SetInt(int64(f.byte()))
+		return s.Interface()
+	case 13:
+		f.panicked = true
+		return fuzzPanicker{[]interface{}{nil, "oops", errors.New("oops"), (*int)(nil)}[f.byte()%4]}
+	case 14:
+		return make(chan int)
+	case 15:
+		return func() {}
+	case 16:
+		return unsafe.Pointer(nil)
+	case 17:
+		// With UseStringer, the reflect.Value's String method
+		// is called instead of PrettyPrint methods within it.
+		panicked := f.panicked
+		v := reflect.ValueOf(f.value(depth + 1))
+		f.panicked = panicked
+		return v
+	case 18:
+		return [3]interface{}{f.value(depth + 1), f.value(depth + 1)}
+	default:
+		return &sync.Map{}
+	}
+}
+
+// fuzzConfig returns a Config with options chosen by the bits of b.
+func fuzzConfig(b byte) Config {
+	return Config{
+		InlineUnder:     int(b&1) * 40,
+		MaxOutputBytes:  int(b>>1&1) * 50,
+		MaxStringLen:    int(b>>2&1) * 3,
+		SyncMaps:        b>>1&1 == 1,
+		UseStringer:     b>>3&1 == 1,
+		Sparse:          b>>4&1 == 1,
+		OmitZero:        b>>4&1 == 1,
+		DedupPointers:   b>>5&1 == 1,
+		MapSort:         MapOrder(b >> 6 & 1),
+		ShowIndirection: b>>7&1 == 1,
+		VerboseCycles:   b>>7&1 == 1,
+		ShowTopType:     b>>3&1 == 1,
+	}
+}
+
+func FuzzFprint(f *testing.F) {
+	f.Add([]byte{0})
+	f.Add([]byte{0, 13, 0})
+	f.Add([]byte{0xff, 8, 3, 1, 5, 2, 9, 12})
+	f.Add([]byte{0x2a, 10, 8, 2, 11, 0, 11, 0})
+	f.Add([]byte{0x01, 9, 3, 2, 1, 13, 0, 0, 17, 12, 10, 4, 1})
+	f.Add([]byte{0x80, 18, 13, 1, 19, 14, 15, 16, 6, 7})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fv := &fuzzValue{data: data}
+		cfg := fuzzConfig(fv.byte())
+		v := fv.value(0)
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Fprint panicked: %v", r)
+			}
+		}()
+		err := With(cfg).Fprint(new(bytes.Buffer), v)
+		if fv.panicked && err == nil {
+			// A PrettyPrint method may not be called
+			// if the output is truncated first.
+			if cfg.MaxOutputBytes == 0 {
+				t.Errorf("Fprint returned nil after a PrettyPrint panic")
+			}
+		}
+	})
+}
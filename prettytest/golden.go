@@ -0,0 +1,73 @@
+// Package prettytest provides helpers for tests
+// that compare pretty-printed values.
+// It is separate from package pretty so that
+// package pretty does not depend on package testing.
+package prettytest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/pretty"
+)
+
+// Update, if true, causes Golden to write golden files
+// rather than compare against them.
+// Golden also writes them if the environment variable PRETTY_UPDATE
+// is set to a true value, such as 1,
+// or if the test binary defines a boolean -update flag and it is set.
+var Update = false
+
+// Golden compares pretty.String(v) to the contents of the file golden,
+// failing the test with a pretty.DiffText if they differ.
+//
+// If Update is set, Golden instead writes pretty.String(v)
+// to the file, creating its parent directories if needed.
+//
+// Trailing newlines are ignored when comparing,
+// and the file is written with a single trailing newline.
+func Golden(t testing.TB, golden string, v interface{}) {
+	t.Helper()
+	got := pretty.String(v)
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(golden), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(got+"\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (set PRETTY_UPDATE=1 to create it)", err)
+	}
+	want := strings.TrimRight(string(data), "\n")
+	if d := pretty.DiffText(want, strings.TrimRight(got, "\n")); d != "" {
+		t.Errorf("%s differs (-want +got):\n%s", golden, d)
+	}
+}
+
+// updating returns whether golden files should be written, as described by Update.
+func updating() bool {
+	if Update {
+		return true
+	}
+	if b, err := strconv.ParseBool(os.Getenv("PRETTY_UPDATE")); err == nil && b {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	g, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	b, ok := g.Get().(bool)
+	return ok && b
+}
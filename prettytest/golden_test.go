@@ -0,0 +1,79 @@
+package prettytest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// update is defined as by a test package that uses Golden,
+// which must not conflict with package prettytest.
+var update = flag.Bool("update", false, "update golden files")
+
+// A recorder records the failures of a test.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed = true
+}
+
+func TestGolden(t *testing.T) {
+	type T struct {
+		A int
+		B []string
+	}
+	golden := filepath.Join(t.TempDir(), "testdata", "t.golden")
+
+	Update = true
+	Golden(t, golden, T{A: 1, B: []string{"x"}})
+	Update = false
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "T{\n\tA: 1\n\tB: [\n\t\t\"x\"\n\t]\n}\n"
+	if string(data) != want {
+		t.Fatalf("wrote %q, want %q", data, want)
+	}
+
+	// Trailing newlines in the file are ignored.
+	if err := os.WriteFile(golden, []byte(strings.TrimSuffix(want, "\n")+"\n\n\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var r recorder
+	Golden(&r, golden, T{A: 1, B: []string{"x"}})
+	if r.failed {
+		t.Errorf("Golden failed for an equal value")
+	}
+
+	r = recorder{}
+	Golden(&r, golden, T{A: 2, B: []string{"x"}})
+	if !r.failed {
+		t.Errorf("Golden did not fail for a different value")
+	}
+}
+
+func TestGoldenUpdateSources(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "t.golden")
+	for _, set := range []func(bool){
+		func(b bool) { Update = b },
+		func(b bool) { t.Setenv("PRETTY_UPDATE", strconv.FormatBool(b)) },
+		func(b bool) { *update = b },
+	} {
+		os.Remove(golden)
+		set(true)
+		Golden(t, golden, 1)
+		set(false)
+		if _, err := os.Stat(golden); err != nil {
+			t.Errorf("Golden did not write the file: %v", err)
+		}
+	}
+}
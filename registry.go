@@ -0,0 +1,218 @@
+package pretty
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var formatters = struct {
+	sync.RWMutex
+	m map[reflect.Type]func(reflect.Value) string
+}{m: make(map[reflect.Type]func(reflect.Value) string)}
+
+// RegisterFormatter registers a function that returns the string
+// printed for values of type t, overriding the default pretty-print format.
+// If f is nil, any formatter registered for t is removed.
+//
+// Formatters are global to the program.
+// RegisterFormatter is safe to call concurrently,
+// for example from the init functions of different packages.
+// If more than one formatter is registered for the same type,
+// the last registration wins.
+//
+// A registered formatter takes precedence over PrettyPrint and String methods.
+func RegisterFormatter(t reflect.Type, f func(v reflect.Value) string) {
+	formatters.Lock()
+	defer formatters.Unlock()
+	if f == nil {
+		delete(formatters.m, t)
+	} else {
+		formatters.m[t] = f
+	}
+}
+
+// formatter returns the formatter registered for t, if any.
+func formatter(t reflect.Type) (func(reflect.Value) string, bool) {
+	formatters.RLock()
+	defer formatters.RUnlock()
+	f, ok := formatters.m[t]
+	return f, ok
+}
+
+var enums = struct {
+	sync.RWMutex
+	m map[reflect.Type]map[int64]string
+}{m: make(map[reflect.Type]map[int64]string)}
+
+// RegisterEnum registers the names of the values of the integer type t,
+// which are printed in place of the numbers; for example,
+// Red rather than 1, or Color(Red) if ShowNamedScalars is true.
+// Values with no name are printed as numbers.
+// If names is nil, any names registered for t are removed.
+//
+// As with RegisterFormatter, names are global to the program,
+// RegisterEnum is safe to call concurrently,
+// and the last registration for a type wins.
+//
+// Names are used only if t has no registered formatter,
+// PrettyPrint method, or String method that is used.
+func RegisterEnum(t reflect.Type, names map[int64]string) {
+	enums.Lock()
+	defer enums.Unlock()
+	if names == nil {
+		delete(enums.m, t)
+		return
+	}
+	m := make(map[int64]string, len(names))
+	for k, v := range names {
+		m[k] = v
+	}
+	enums.m[t] = m
+}
+
+// enumName returns the name registered for the integer value v, if any.
+func enumName(v reflect.Value) (string, bool) {
+	enums.RLock()
+	defer enums.RUnlock()
+	names, ok := enums.m[v.Type()]
+	if !ok {
+		return "", false
+	}
+	var n int64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = v.Int()
+	default:
+		n = int64(v.Uint())
+	}
+	name, ok := names[n]
+	return name, ok
+}
+
+// A flag is a named set of bits of a bitmask type.
+type flag struct {
+	bits uint64
+	name string
+}
+
+var flagSets = struct {
+	sync.RWMutex
+	m map[reflect.Type][]flag
+}{m: make(map[reflect.Type][]flag)}
+
+// RegisterFlags registers the names of the flags of the integer bitmask type t.
+// Values of t are printed as the names of the flags they contain, joined by |,
+// followed by any remaining bits in hex; for example,
+// Exec|Read rather than 5, Read|0x10 rather than 20,
+// or Perm(Exec|Read) if ShowNamedScalars is true.
+// The name for 0, if any, is printed for the value 0.
+// Flags are matched in increasing order of their values,
+// and a flag matches if all of its bits are in the value
+// and not in an earlier matching flag.
+// If names is nil, any flags registered for t are removed.
+//
+// Flags are registered as described by RegisterEnum.
+// If t has both, a value with a name registered by RegisterEnum
+// is printed by that name.
+func RegisterFlags(t reflect.Type, names map[int64]string) {
+	flagSets.Lock()
+	defer flagSets.Unlock()
+	if names == nil {
+		delete(flagSets.m, t)
+		return
+	}
+	var fs []flag
+	for k, v := range names {
+		fs = append(fs, flag{bits: uint64(k), name: v})
+	}
+	sort.Slice(fs, func(i, j int) bool { return fs[i].bits < fs[j].bits })
+	flagSets.m[t] = fs
+}
+
+// integerName returns the name registered for the integer value v,
+// by RegisterEnum or RegisterFlags, if any.
+func integerName(v reflect.Value) (string, bool) {
+	if name, ok := enumName(v); ok {
+		return name, true
+	}
+	return flagNames(v)
+}
+
+// flagNames returns the flags registered for v's type contained in v,
+// joined by |, if any are registered.
+func flagNames(v reflect.Value) (string, bool) {
+	flagSets.RLock()
+	defer flagSets.RUnlock()
+	fs, ok := flagSets.m[v.Type()]
+	if !ok {
+		return "", false
+	}
+	var n uint64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = uint64(v.Int())
+		if bits := v.Type().Bits(); bits < 64 {
+			n &= 1<<uint(bits) - 1
+		}
+	default:
+		n = v.Uint()
+	}
+	var names []string
+	rest := n
+	for _, f := range fs {
+		if f.bits == 0 {
+			if n == 0 {
+				return f.name, true
+			}
+			continue
+		}
+		if rest&f.bits == f.bits {
+			names = append(names, f.name)
+			rest &^= f.bits
+		}
+	}
+	if rest != 0 || len(names) == 0 {
+		names = append(names, fmt.Sprintf("%#x", rest))
+	}
+	return strings.Join(names, "|"), true
+}
+
+var unions = struct {
+	sync.RWMutex
+	m map[reflect.Type]bool
+}{m: make(map[reflect.Type]bool)}
+
+// RegisterUnion registers the interface type t as a union,
+// such as the interface of a protocol buffer oneof field,
+// whose values are one of several variant types.
+// A struct field of type t is printed on a single line
+// as its name, =, and its value with the value's type,
+// omitting any pointer; for example, Kind = Text{Body: "hi"},
+// or Size = Bytes(5) for a variant that is not a struct.
+// If union is false, t is no longer registered as a union.
+//
+// As with RegisterFormatter, unions are global to the program,
+// and RegisterUnion is safe to call concurrently.
+// RegisterUnion panics if t is not an interface type.
+func RegisterUnion(t reflect.Type, union bool) {
+	if t.Kind() != reflect.Interface {
+		panic("pretty: RegisterUnion of non-interface type " + t.String())
+	}
+	unions.Lock()
+	defer unions.Unlock()
+	if !union {
+		delete(unions.m, t)
+		return
+	}
+	unions.m[t] = true
+}
+
+// isUnion returns whether t is registered as a union.
+func isUnion(t reflect.Type) bool {
+	unions.RLock()
+	defer unions.RUnlock()
+	return unions.m[t]
+}
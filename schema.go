@@ -0,0 +1,117 @@
+package pretty
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Schema returns an outline of the type of a value, without its data.
+//
+// Struct fields are listed with their types,
+// using the same layout as Fprint.
+// Each named struct, pointer, slice, array, or map type
+// is expanded only once;
+// later occurrences, including recursive ones, print just the type name.
+// Unnamed struct types are printed as struct {…}, with their fields.
+// The fields listed are those that Fprint would print if they were non-empty.
+func Schema(v interface{}) string {
+	return defaultRenderer().Schema(v)
+}
+
+// Schema returns an outline of the type of a value,
+// as described by the package-level Schema,
+// using the options of the Renderer's Config.
+func (r *Renderer) Schema(v interface{}) string {
+	buf := bytes.NewBuffer(nil)
+	r.FprintSchema(buf, v)
+	return buf.String()
+}
+
+// FprintSchema prints an outline of the type of a value to an io.Writer,
+// as described by the package-level Schema,
+// returning the error from writing it, if any.
+func (r *Renderer) FprintSchema(out io.Writer, v interface{}) error {
+	s := &schema{cfg: &r.cfg, out: &errWriter{w: out}, done: make(map[reflect.Type]bool)}
+	s.print(r.cfg.Newline, reflect.TypeOf(v))
+	return s.out.err
+}
+
+type schema struct {
+	cfg *Config
+	out *errWriter
+	// done is the set of named types that have been expanded.
+	done map[reflect.Type]bool
+}
+
+// pr prints to s.out.
+// Write errors are recorded by s.out, which ignores later writes.
+func (s *schema) pr(f string, args ...interface{}) {
+	fmt.Fprintf(s.out, f, args...)
+}
+
+func (s *schema) print(indent string, t reflect.Type) {
+	if t == nil {
+		s.pr("nil")
+		return
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		if t.Name() == "" {
+			break
+		}
+		if s.done[t] {
+			s.pr("%s", t.Name())
+			return
+		}
+		s.done[t] = true
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		s.pr("*")
+		s.print(indent, t.Elem())
+
+	case reflect.Slice:
+		s.pr("[]")
+		s.print(indent, t.Elem())
+
+	case reflect.Array:
+		s.pr("[%d]", t.Len())
+		s.print(indent, t.Elem())
+
+	case reflect.Map:
+		s.pr("map[%s]", t.Key())
+		s.print(indent, t.Elem())
+
+	case reflect.Struct:
+		s.printStruct(indent, t)
+
+	default:
+		s.pr("%s", t)
+	}
+}
+
+func (s *schema) printStruct(indent string, t reflect.Type) {
+	name := t.Name()
+	if name == "" {
+		name = "struct "
+	}
+	open, close := s.cfg.delims(reflect.Struct)
+	s.pr("%s%s", name, open)
+	indent2 := indent + s.cfg.Indent
+	var n int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !s.cfg.visible(&f) {
+			continue
+		}
+		n++
+		s.pr("%s%s: ", indent2, f.Name)
+		s.print(indent2, f.Type)
+	}
+	if n == 0 {
+		indent = ""
+	}
+	s.pr("%s%s", indent, close)
+}
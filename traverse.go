@@ -0,0 +1,327 @@
+package pretty
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// The values within a value are decided in one place, here,
+// so that Fprint, Walk, Nodes, HTML, FprintGo,
+// and the pointer counting for DedupPointers agree on them.
+// kind and elems say how a value is traversed and what is within it.
+// traverse uses them to visit a value and the values within it;
+// the printer, which lays out each composite value itself,
+// uses them directly.
+
+// A nodeKind is the way a value is traversed.
+type nodeKind int
+
+const (
+	// scalarNode is a value with no values within it:
+	// a bool, number, string, channel, function, or unsafe pointer,
+	// a nil pointer, interface, or slice, or the zero Value.
+	scalarNode nodeKind = iota
+
+	// customNode is a value printed by a registered formatter
+	// or by one of its methods, such as PrettyPrint.
+	// The values within it are not visited.
+	customNode
+
+	// nullNode is a null wrapper that is not Valid, for NullWrappers.
+	nullNode
+
+	// refNode is a non-nil pointer or interface, or a Valid null wrapper.
+	// Its single elem is printed in its place.
+	refNode
+
+	// arrayNode is an array or a non-nil slice.
+	arrayNode
+
+	// structNode is a struct.
+	structNode
+
+	// gettersNode is a struct printed by its getters, for CallGetters.
+	gettersNode
+
+	// mapNode is a map, or a sync.Map if SyncMaps is true.
+	mapNode
+)
+
+// A traversal holds the state shared by the values within a value
+// while it is traversed or printed.
+type traversal struct {
+	c *Config
+
+	// literal is whether the traversal is for FprintGo,
+	// which does not use formatters, methods,
+	// or the options that change how structs are traversed,
+	// and visits every exported struct field.
+	literal bool
+
+	// getters caches the results of the getters of each struct,
+	// keyed by getterKey, so that each getter is called once.
+	getters map[interface{}][]getter
+}
+
+// kind returns the way v is traversed.
+// If raw is true, formatters and methods are not used.
+// If inGetter is true, v is within the result of a getter,
+// so its getters are not called.
+func (t *traversal) kind(v reflect.Value, raw, inGetter bool) nodeKind {
+	if !v.IsValid() {
+		return scalarNode
+	}
+	if !raw && !t.literal {
+		if _, ok := t.c.custom(v); ok {
+			return customNode
+		}
+	}
+	switch v.Kind() {
+	case reflect.Array:
+		return arrayNode
+	case reflect.Slice:
+		if v.IsNil() {
+			return scalarNode
+		}
+		return arrayNode
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return scalarNode
+		}
+		return refNode
+	case reflect.Map:
+		return mapNode
+	case reflect.Struct:
+		return t.structKind(v, inGetter)
+	default:
+		return scalarNode
+	}
+}
+
+// structKind returns the way the struct v is traversed.
+func (t *traversal) structKind(v reflect.Value, inGetter bool) nodeKind {
+	c := t.c
+	switch {
+	case t.literal:
+		return structNode
+	case c.SyncMaps && v.Type() == syncMapType && v.CanInterface():
+		return mapNode
+	}
+	if c.NullWrappers {
+		if _, ok := nullWrapper(v.Type()); ok {
+			if !v.FieldByName("Valid").Bool() {
+				return nullNode
+			}
+			return refNode
+		}
+	}
+	if c.CallGetters && !inGetter && v.CanInterface() {
+		if fields, omitted := c.structFields(v); len(fields) == 0 && !omitted && len(t.callGetters(v)) > 0 {
+			return gettersNode
+		}
+	}
+	return structNode
+}
+
+// An elem is a value within a composite value.
+type elem struct {
+	v reflect.Value
+
+	// step is the location of v within the composite value:
+	// a struct field name, a getter name followed by (),
+	// an array or slice index, or a map key.
+	// It is nil for the elem of a pointer or interface.
+	step interface{}
+
+	// field is the struct field holding v, if any.
+	field *structField
+
+	// key is whether v is a map key, rather than a map value.
+	key bool
+
+	// failed is whether the getter giving v panicked,
+	// in which case v is the zero Value.
+	failed bool
+}
+
+// elems returns the values within v, which is of kind k,
+// in the order that they are printed.
+// The entries of a map are each a key followed by its value.
+func (t *traversal) elems(v reflect.Value, k nodeKind) []elem {
+	var es []elem
+	switch k {
+	case refNode:
+		if v.Kind() != reflect.Struct {
+			return []elem{{v: v.Elem()}}
+		}
+		i, _ := nullWrapper(v.Type())
+		return []elem{{v: v.Field(i), step: v.Type().Field(i).Name}}
+
+	case arrayNode:
+		for i := 0; i < v.Len(); i++ {
+			es = append(es, elem{v: v.Index(i), step: i})
+		}
+
+	case structNode:
+		var fields []structField
+		if t.literal {
+			fields = exportedFields(v)
+		} else {
+			fields, _ = t.c.structFields(v)
+		}
+		for i := range fields {
+			f := &fields[i]
+			es = append(es, elem{v: f.v, step: f.Name, field: f})
+		}
+
+	case gettersNode:
+		for _, g := range t.callGetters(v) {
+			es = append(es, elem{v: g.v, step: g.name + "()", failed: !g.ok})
+		}
+
+	case mapNode:
+		for _, e := range t.mapEntries(v) {
+			es = append(es, elem{v: e.k, step: e.k, key: true}, elem{v: e.v, step: e.k})
+		}
+	}
+	return es
+}
+
+// exportedFields returns every exported field of the struct v.
+func exportedFields(v reflect.Value) []structField {
+	var fields []structField
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); exported(&f) {
+			fields = append(fields, structField{StructField: f, v: v.Field(i), qualified: f.Name})
+		}
+	}
+	return fields
+}
+
+// mapEntries returns the entries of v, a map or, if SyncMaps is true,
+// a sync.Map, in the order described by sortEntries.
+func (t *traversal) mapEntries(v reflect.Value) []mapEntry {
+	if v.Kind() == reflect.Map {
+		es := make([]mapEntry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			es = append(es, mapEntry{k: iter.Key(), v: iter.Value()})
+		}
+		return t.sortEntries(es)
+	}
+	if !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	// Keys and values are held in a []interface{},
+	// so their Values are of kind Interface, as in a map[interface{}]interface{}.
+	var kvs []interface{}
+	v.Addr().Interface().(*sync.Map).Range(func(k, v interface{}) bool {
+		kvs = append(kvs, k, v)
+		return true
+	})
+	s := reflect.ValueOf(kvs)
+	es := make([]mapEntry, 0, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		es = append(es, mapEntry{k: s.Index(i), v: s.Index(i + 1)})
+	}
+	return t.sortEntries(es)
+}
+
+// compactString returns v printed on a single line.
+func (t *traversal) compactString(v reflect.Value) string {
+	buf := bytes.NewBuffer(nil)
+	p := &printer{cfg: t.c, t: t, out: &errWriter{w: buf}, path: make(map[identity]string), compact: true}
+	p.print("", v)
+	return buf.String()
+}
+
+// stepString returns an elem's step as it is written in a location:
+// a string map key scrubbed by ScrubString,
+// other map keys printed on a single line,
+// and other steps, such as field names and indices, as by fmt.Sprint.
+func (t *traversal) stepString(step interface{}) string {
+	if k, ok := step.(reflect.Value); ok {
+		if k.Kind() == reflect.String {
+			return t.c.scrub(k.String())
+		}
+		return t.compactString(k)
+	}
+	return fmt.Sprint(step)
+}
+
+// A node is a value visited by traverse.
+type node struct {
+	elem
+	kind nodeKind
+
+	// parent is the node holding this one, or nil for the root.
+	parent *node
+
+	// index is the position of the node's elem among its parent's elems.
+	index int
+
+	// cycle is whether the value is already on the path from the root,
+	// in which case the values within it are not visited.
+	cycle bool
+
+	// raw is whether formatters and methods are not used,
+	// because the value is within a field tagged `pretty:"raw"`.
+	raw bool
+
+	// inGetter is whether the value is within the result of a getter.
+	inGetter bool
+}
+
+// A visitor receives the values visited by traverse.
+type visitor interface {
+	// visit is called for each value,
+	// and returns whether to visit the values within it.
+	visit(n *node) bool
+
+	// leave is called after the values within a value are visited,
+	// if visit returned true for it.
+	leave(n *node)
+
+	// stopped returns whether the traversal should stop.
+	stopped() bool
+}
+
+// traverse visits v and the values within it, pruning cycles.
+func (t *traversal) traverse(vis visitor, v reflect.Value) {
+	t.visit(vis, &node{elem: elem{v: v}}, make(map[identity]bool))
+}
+
+func (t *traversal) visit(vis visitor, n *node, path map[identity]bool) {
+	n.kind = t.kind(n.v, n.raw, n.inGetter)
+	if id, ok := identify(n.v); ok {
+		if path[id] {
+			n.cycle = true
+			vis.visit(n)
+			return
+		}
+		path[id] = true
+		defer delete(path, id)
+	}
+	if !vis.visit(n) {
+		return
+	}
+	for i, e := range t.elems(n.v, n.kind) {
+		if vis.stopped() {
+			return
+		}
+		child := &node{
+			elem:     e,
+			parent:   n,
+			index:    i,
+			raw:      n.raw || e.field != nil && hasTag(e.field.StructField, "raw"),
+			inGetter: n.inGetter || n.kind == gettersNode,
+		}
+		t.visit(vis, child, path)
+	}
+	vis.leave(n)
+}
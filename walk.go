@@ -0,0 +1,104 @@
+package pretty
+
+import (
+	"reflect"
+)
+
+// A Visitor receives callbacks from Walk as it traverses a value.
+//
+// Composite values — structs, arrays, slices, and maps — are bracketed by
+// a call to one of the Enter methods and a matching call to Leave.
+// All other values are passed to Scalar.
+type Visitor interface {
+	// EnterStruct is called before visiting the fields of a struct.
+	EnterStruct(t reflect.Type)
+
+	// EnterArray is called before visiting the elements of an array or slice.
+	EnterArray(t reflect.Type)
+
+	// EnterMap is called before visiting the entries of a map.
+	EnterMap(t reflect.Type)
+
+	// Field is called before visiting the value of a struct field.
+	Field(name string)
+
+	// Key is called before visiting the value of a map entry.
+	Key(k reflect.Value)
+
+	// Scalar is called for each non-composite value.
+	// It is also called for nil values, in which case v is either
+	// the zero Value or a nil pointer, interface, or slice,
+	// and for values that Fprint prints with a PrettyPrint or String method.
+	Scalar(v reflect.Value)
+
+	// Cycle is called in place of visiting a value
+	// that is already on the path from the root.
+	Cycle(v reflect.Value)
+
+	// Leave is called after visiting the fields, elements,
+	// or entries of a composite value.
+	Leave()
+}
+
+// Walk traverses a value, calling the methods of a Visitor.
+//
+// Walk shares its traversal rules with Fprint:
+// unexported and empty struct fields are not visited,
+// map entries are visited in the same order that Fprint prints them,
+// values that Fprint prints with a PrettyPrint or String method
+// are not traversed,
+// cycles are pruned, and a reflect.Value is walked as the value it holds.
+func Walk(v interface{}, vis Visitor) {
+	defaultRenderer().Walk(v, vis)
+}
+
+// Walk traverses a value, calling the methods of a Visitor,
+// as described by the package-level Walk,
+// using the options of the Renderer's Config.
+func (r *Renderer) Walk(v interface{}, vis Visitor) {
+	(&traversal{c: &r.cfg}).walk(vis, valueOf(v))
+}
+
+// walk traverses v, calling the methods of vis.
+func (t *traversal) walk(vis Visitor, v reflect.Value) {
+	t.traverse(walker{vis}, v)
+}
+
+// A walker calls the methods of a Visitor for the nodes of a traversal.
+type walker struct{ vis Visitor }
+
+func (w walker) visit(n *node) bool {
+	if n.key {
+		w.vis.Key(n.v)
+		return false
+	}
+	if n.parent != nil && (n.parent.kind == structNode || n.parent.kind == gettersNode) {
+		w.vis.Field(n.step.(string))
+	}
+	switch {
+	case n.cycle:
+		w.vis.Cycle(n.v)
+		return false
+	case n.kind == refNode:
+		// The value referred to is visited in its place.
+		return true
+	case n.kind == arrayNode:
+		w.vis.EnterArray(n.v.Type())
+	case n.kind == structNode || n.kind == gettersNode:
+		w.vis.EnterStruct(n.v.Type())
+	case n.kind == mapNode:
+		w.vis.EnterMap(n.v.Type())
+	default:
+		w.vis.Scalar(n.v)
+		return false
+	}
+	return true
+}
+
+func (w walker) leave(n *node) {
+	if n.kind != refNode {
+		w.vis.Leave()
+	}
+}
+
+func (walker) stopped() bool { return false }